@@ -0,0 +1,328 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snapshot are the metrics of an index, pushed to an aggregation server
+// after each build of a repository:
+//
+//	view-annotated-file -format snapshot build.log |
+//		curl -H "Authorization: Bearer $TOKEN" --data-binary @- "https://host/api/v1/snapshots?repo=app&revision=$SHA"
+type Snapshot struct {
+	Repo     string    `json:"repo"`
+	Revision string    `json:"revision,omitempty"`
+	Time     time.Time `json:"time"`
+
+	Files       int   `json:"files"`
+	Notes       int   `json:"notes"`
+	HeapEscapes int   `json:"heapEscapes"`
+	Stats       Stats `json:"stats"`
+
+	Metadata Metadata `json:"metadata"`
+}
+
+// Snapshot returns the metrics of the index.
+func (index *Index) Snapshot() *Snapshot {
+	snapshot := &Snapshot{Time: index.Modified.UTC(), Metadata: index.Metadata}
+	for _, file := range index.Files {
+		snapshot.Files++
+		snapshot.Notes += len(file.Notes)
+		for i, v := range file.Stats {
+			snapshot.Stats[i][0] += v[0]
+			snapshot.Stats[i][1] += v[1]
+		}
+	}
+	snapshot.HeapEscapes = index.HeapEscapes()
+	return snapshot
+}
+
+// maxSnapshotSize limits the body of a pushed snapshot.
+const maxSnapshotSize = 1 << 20
+
+// repoPattern are the allowed repository names, e.g. "org/app".
+var repoPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*(/[A-Za-z0-9][A-Za-z0-9._-]*)*$`)
+
+// Aggregator stores the snapshots pushed by many repositories and serves
+// a dashboard of their metrics.
+type Aggregator struct {
+	// Dir has a directory of snapshots for each repository.
+	Dir string
+	// Tokens are the bearer tokens allowed to push snapshots.
+	Tokens   []string
+	Template *template.Template
+
+	mu        sync.Mutex
+	snapshots map[string][]*Snapshot // by repository, oldest first
+}
+
+// AggregatePage is the data of the aggregation dashboard.
+type AggregatePage struct {
+	Stats [statCount]Stat
+	Repos []RepoSummary
+}
+
+// RepoSummary is the latest snapshot of a repository and the change since
+// the one before.
+type RepoSummary struct {
+	*Snapshot
+	Previous  *Snapshot
+	Snapshots int
+}
+
+// EscapesDelta is the change of heap escapes since the previous snapshot.
+func (repo RepoSummary) EscapesDelta() int {
+	if repo.Previous == nil {
+		return 0
+	}
+	return repo.HeapEscapes - repo.Previous.HeapEscapes
+}
+
+// Aggregate implements the aggregate subcommand, which serves the
+// aggregation server until it fails.
+func Aggregate(args []string) error {
+	set := flag.NewFlagSet("aggregate", flag.ExitOnError)
+	addr := set.String("http", ":8080", "listen on http")
+	dir := set.String("dir", "snapshots", "directory to store the snapshots in")
+	var tokens StringList
+	set.Var(&tokens, "token", "bearer token allowed to push snapshots, can be repeated; the AGGREGATE_TOKENS environment variable can hold comma-separated tokens too")
+	set.Parse(args)
+
+	if env := os.Getenv("AGGREGATE_TOKENS"); env != "" {
+		tokens = append(tokens, strings.Split(env, ",")...)
+	}
+	if len(tokens) == 0 {
+		return errors.New("aggregate: at least one -token is required to push snapshots")
+	}
+
+	tmpl, err := LoadTemplate(*assetsDir)
+	if err != nil {
+		return err
+	}
+	aggregator := &Aggregator{Dir: *dir, Tokens: tokens, Template: tmpl}
+	if err := aggregator.Load(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Aggregating on %v\n", *addr)
+	server := &http.Server{
+		Addr:    *addr,
+		Handler: aggregator,
+		// snapshots are small, slow clients mustn't hold connections
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Minute,
+	}
+	return server.ListenAndServe()
+}
+
+// Load reads the stored snapshots.
+func (aggregator *Aggregator) Load() error {
+	aggregator.snapshots = make(map[string][]*Snapshot)
+	if err := os.MkdirAll(aggregator.Dir, 0755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(aggregator.Dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		names, err := filepath.Glob(filepath.Join(aggregator.Dir, entry.Name(), "*.json"))
+		if err != nil {
+			return err
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			data, err := os.ReadFile(name)
+			if err != nil {
+				return err
+			}
+			snapshot := &Snapshot{}
+			if err := json.Unmarshal(data, snapshot); err != nil {
+				return fmt.Errorf("%v: %v", name, err)
+			}
+			aggregator.snapshots[snapshot.Repo] = append(aggregator.snapshots[snapshot.Repo], snapshot)
+		}
+	}
+	return nil
+}
+
+// Store saves a snapshot in the directory of its repository, mu must be
+// held.
+func (aggregator *Aggregator) Store(snapshot *Snapshot) error {
+	dir := filepath.Join(aggregator.Dir, url.PathEscape(snapshot.Repo))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	// names sort in the order the snapshots were received
+	name := filepath.Join(dir, fmt.Sprintf("%020d.json", snapshot.Time.UnixNano()))
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		return err
+	}
+	aggregator.snapshots[snapshot.Repo] = append(aggregator.snapshots[snapshot.Repo], snapshot)
+	return nil
+}
+
+func (aggregator *Aggregator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// pushes are read before locking, so that slow clients don't block
+	if r.URL.Path == "/api/v1/snapshots" && r.Method == http.MethodPost {
+		aggregator.servePush(w, r)
+		return
+	}
+
+	aggregator.mu.Lock()
+	defer aggregator.mu.Unlock()
+
+	switch r.URL.Path {
+	case "", "/":
+		aggregator.serveDashboard(w, r)
+	case "/api/v1/snapshots":
+		if repo := r.FormValue("repo"); repo != "" {
+			snapshots, ok := aggregator.snapshots[repo]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprintf(w, "Unknown repository %q.", repo)
+				return
+			}
+			writeJSON(w, snapshots)
+			return
+		}
+		latest := []*Snapshot{}
+		for _, repo := range aggregator.summaries() {
+			latest = append(latest, repo.Snapshot)
+		}
+		writeJSON(w, latest)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// servePush stores a snapshot pushed with a valid token, mu is only held
+// while storing it.
+func (aggregator *Aggregator) servePush(w http.ResponseWriter, r *http.Request) {
+	if !aggregator.authorize(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintf(w, "Invalid token.")
+		return
+	}
+
+	// the body is the snapshot, not a form, whatever its content type
+	query := r.URL.Query()
+	repo := query.Get("repo")
+	if !repoPattern.MatchString(repo) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid repository %q.", repo)
+		return
+	}
+
+	snapshot := &Snapshot{}
+	data, err := readPush(w, r)
+	if err == nil {
+		err = json.Unmarshal(data, snapshot)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid snapshot: %v", err)
+		return
+	}
+	snapshot.Repo = repo
+	snapshot.Revision = query.Get("revision")
+
+	aggregator.mu.Lock()
+	snapshot.Time = time.Now().UTC()
+	err = aggregator.Store(snapshot)
+	count := len(aggregator.snapshots[repo])
+	aggregator.mu.Unlock()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		fmt.Fprintf(w, "Error: %v", err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintf(w, "Stored snapshot %d of %v.", count, repo)
+}
+
+// readPush reads the body of a push, which may be gzip-encoded. The size
+// limit applies to the body as sent and after decompressing it.
+func readPush(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	body := http.MaxBytesReader(w, r.Body, maxSnapshotSize)
+	switch encoding := r.Header.Get("Content-Encoding"); strings.ToLower(encoding) {
+	case "", "identity":
+		return io.ReadAll(body)
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(zr, maxSnapshotSize+1))
+		if err == nil && len(data) > maxSnapshotSize {
+			err = fmt.Errorf("larger than %d bytes", maxSnapshotSize)
+		}
+		return data, err
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", encoding)
+	}
+}
+
+// authorize checks the bearer token of a push.
+func (aggregator *Aggregator) authorize(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	authorized := false
+	for _, allowed := range aggregator.Tokens {
+		if allowed != "" && subtle.ConstantTimeCompare([]byte(token), []byte(allowed)) == 1 {
+			authorized = true
+		}
+	}
+	return authorized
+}
+
+// summaries returns the latest snapshot of each repository, the ones with
+// the most heap escapes first.
+func (aggregator *Aggregator) summaries() []RepoSummary {
+	repos := []RepoSummary{}
+	for _, snapshots := range aggregator.snapshots {
+		repo := RepoSummary{Snapshot: snapshots[len(snapshots)-1], Snapshots: len(snapshots)}
+		if len(snapshots) > 1 {
+			repo.Previous = snapshots[len(snapshots)-2]
+		}
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, k int) bool {
+		if repos[i].HeapEscapes != repos[k].HeapEscapes {
+			return repos[i].HeapEscapes > repos[k].HeapEscapes
+		}
+		return repos[i].Repo < repos[k].Repo
+	})
+	return repos
+}
+
+func (aggregator *Aggregator) serveDashboard(w http.ResponseWriter, r *http.Request) {
+	page := &AggregatePage{Stats: statSpecs, Repos: aggregator.summaries()}
+	if err := aggregator.Template.ExecuteTemplate(w, "aggregate.html", page); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"time"
+)
+
+// allocLintTool is the tool of the notes added by LintAllocations.
+const allocLintTool = "alloclint"
+
+// fmtAllocating are the fmt functions returning a newly allocated result.
+var fmtAllocating = map[string]bool{
+	"Sprintf": true, "Sprint": true, "Sprintln": true, "Errorf": true,
+}
+
+// LintAllocations adds notes about common allocation patterns in loops to
+// the packages of the annotated Go files: fmt.Sprintf and friends, and
+// string concatenation with + or +=, which copies the string every time.
+// The packages are type-checked to tell strings from numbers. Packages
+// linted before are skipped, so that the notes of logs parsed while serving
+// are linted without checking all packages again, see Server.Parse.
+func (index *Index) LintAllocations() {
+	linter := newAllocLinter()
+	index.addAllocLints(linter.Lint(index.sources(), index.allocLintDirs()))
+}
+
+// allocLintDirs returns the directories of the packages that are not linted
+// yet and marks them as linted. Like for the other analyses, packages in
+// GOROOT and the module cache are not linted, see PackageDirs.
+func (index *Index) allocLintDirs() []string {
+	if index.linted == nil {
+		index.linted = make(map[string]bool)
+	}
+	var dirs []string
+	for _, dir := range index.PackageDirs() {
+		if !index.linted[dir] {
+			index.linted[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// allocLint is a note found by an allocLinter.
+type allocLint struct {
+	Path    string
+	Line    int // 1 is the first line
+	Column  int // 1 is the first column
+	Message string
+}
+
+// addAllocLints adds the notes found by an allocLinter to the index.
+func (index *Index) addAllocLints(lints []allocLint) {
+	added := false
+	for _, lint := range lints {
+		_, file := index.File("", lint.Path)
+		if file.AddNote(allocLintTool, lint.Line-1, lint.Column-1, []byte(lint.Message)) {
+			file.Stats.Add([]byte(lint.Message))
+			added = true
+		}
+	}
+
+	if added {
+		index.Sort()
+		index.Generation++
+		index.Modified = time.Now()
+	}
+}
+
+// allocLinter type-checks packages for the allocation lints, importing
+// their dependencies from source once for all packages it lints. It doesn't
+// use the index, so that the server lints without holding its lock.
+type allocLinter struct {
+	fset *token.FileSet
+	imp  types.Importer
+}
+
+func newAllocLinter() *allocLinter {
+	fset := token.NewFileSet()
+	return &allocLinter{
+		fset: fset,
+		imp:  importer.ForCompiler(fset, "source", nil),
+	}
+}
+
+// Lint returns the allocation lints of the packages in dirs of the sources.
+func (linter *allocLinter) Lint(sources fs.FS, dirs []string) []allocLint {
+	var lints []allocLint
+	for _, dir := range dirs {
+		info := &types.Info{
+			Types: make(map[ast.Expr]types.TypeAndValue),
+			Uses:  make(map[*ast.Ident]types.Object),
+		}
+		files := checkPackage(linter.fset, linter.imp, sources, dir, info)
+		report := func(pos token.Pos, msg string) {
+			position := linter.fset.Position(pos)
+			lints = append(lints, allocLint{position.Filename, position.Line, position.Column, msg})
+		}
+		for _, file := range files {
+			lintLoops(info, file, report)
+		}
+	}
+	return lints
+}
+
+// lintLoops reports the allocation patterns in the loops in node.
+func lintLoops(info *types.Info, node ast.Node, report func(token.Pos, string)) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		var body *ast.BlockStmt
+		switch loop := n.(type) {
+		case *ast.ForStmt:
+			body = loop.Body
+		case *ast.RangeStmt:
+			body = loop.Body
+		default:
+			return true
+		}
+		lintLoop(info, body, report)
+		// nested loops are part of the body
+		return false
+	})
+}
+
+// lintLoop reports the allocation patterns in the body of a loop, except
+// in function literals, which may not run in the loop, and in return
+// statements and panics, which leave it.
+func lintLoop(info *types.Info, body *ast.BlockStmt, report func(token.Pos, string)) {
+	// constant expressions are folded by the compiler
+	isString := func(expr ast.Expr) bool {
+		tv, ok := info.Types[expr]
+		if !ok || tv.Type == nil || tv.Value != nil {
+			return false
+		}
+		basic, ok := tv.Type.Underlying().(*types.Basic)
+		return ok && basic.Info()&types.IsString != 0
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// the function may have loops of its own
+			lintLoops(info, n.Body, report)
+			return false
+		case *ast.ReturnStmt:
+			return false
+		case *ast.CallExpr:
+			if ident, ok := n.Fun.(*ast.Ident); ok {
+				if builtin, ok := info.Uses[ident].(*types.Builtin); ok && builtin.Name() == "panic" {
+					return false
+				}
+			}
+			sel, ok := n.Fun.(*ast.SelectorExpr)
+			if !ok || !fmtAllocating[sel.Sel.Name] {
+				return true
+			}
+			fn, ok := info.Uses[sel.Sel].(*types.Func)
+			if ok && fn.Pkg() != nil && fn.Pkg().Path() == "fmt" {
+				report(n.Pos(), "fmt."+sel.Sel.Name+" in a loop allocates on every iteration: use strconv or append to a reused buffer")
+			}
+		case *ast.AssignStmt:
+			if n.Tok == token.ADD_ASSIGN && len(n.Lhs) == 1 && isString(n.Lhs[0]) {
+				report(n.TokPos, "string concatenation in a loop copies the string on every iteration: use a strings.Builder")
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.ADD && isString(n) {
+				report(n.Pos(), "string concatenation in a loop allocates on every iteration: use a strings.Builder or append to a reused buffer")
+				// report a chain of + once
+				return false
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintLoops(t *testing.T) {
+	const src = `package demo
+
+import "fmt"
+
+func Find(xs []int) error {
+	for _, x := range xs {
+		if x < 0 {
+			return fmt.Errorf("negative %d", x)
+		}
+		if x == 0 {
+			panic(fmt.Sprint("zero"))
+		}
+	}
+	return nil
+}
+
+func Label(xs []int) (s string) {
+	for _, x := range xs {
+		s += fmt.Sprint(x)
+	}
+	return s
+}
+
+func Later(xs [][]int) func() string {
+	return func() string {
+		for _, x := range xs {
+			_ = fmt.Sprint(x)
+		}
+		return ""
+	}
+}
+
+func Nested(xs []int) {
+	for range xs {
+		f := func(y int) string { return fmt.Sprint(y) }
+		_ = f
+	}
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "demo.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("demo", fset, []*ast.File{file}, info); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	lintLoops(info, file, func(pos token.Pos, msg string) {
+		got = append(got, fmt.Sprintf("%v: %v", fset.Position(pos).Line, strings.SplitN(msg, " ", 2)[0]))
+	})
+	want := []string{
+		"19: string",
+		"19: fmt.Sprint",
+		"27: fmt.Sprint",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n\t%v\nwant\n\t%v", strings.Join(got, "\n\t"), strings.Join(want, "\n\t"))
+	}
+}
+
+// TestLintAllocationsSkipsGoroot checks that notes in GOROOT don't make the
+// standard library be linted.
+func TestLintAllocationsSkipsGoroot(t *testing.T) {
+	index := NewIndex()
+	goroot := index.Goroot()
+	if goroot == "" {
+		t.Skip("GOROOT not found")
+	}
+	path := filepath.Join(goroot, "src", "net", "url", "url.go")
+	if _, err := os.Stat(path); err != nil {
+		t.Skip(err)
+	}
+	index.Parse(goroot, "", []byte(path+":1:1: can inline f\n"))
+	index.LintAllocations()
+	for _, file := range index.Files {
+		for _, note := range file.Notes {
+			if note.Tool == allocLintTool {
+				t.Errorf("%v:%v: %s", file.Path, note.Line+1, note.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// vetAnalyzers are the analyzers run by go vet, which can be selected with
+// its flags.
+var vetAnalyzers = map[string]bool{
+	"appends": true, "asmdecl": true, "assign": true, "atomic": true,
+	"bools": true, "buildtag": true, "cgocall": true, "composites": true,
+	"copylocks": true, "defers": true, "directive": true, "errorsas": true,
+	"framepointer": true, "httpresponse": true, "ifaceassert": true,
+	"loopclosure": true, "lostcancel": true, "nilfunc": true, "printf": true,
+	"shift": true, "sigchanyzer": true, "slog": true, "stdmethods": true,
+	"stringintconv": true, "structtag": true, "testinggoroutine": true,
+	"tests": true, "timeformat": true, "unmarshal": true, "unreachable": true,
+	"unsafeptr": true, "unusedresult": true,
+}
+
+// analysisPasses is the import path of the analyzers of golang.org/x/tools,
+// each has a command at <name>/cmd/<name>.
+const analysisPasses = "golang.org/x/tools/go/analysis/passes/"
+
+// AnalyzerCommands returns the commands running the analyzers on the
+// packages with go/analysis JSON output, which is parsed like go vet -json.
+// Analyzers of go vet are run by it, other names are commands of the
+// analyzers of golang.org/x/tools, e.g. "nilness" or "shadow", and names
+// with a slash are the import paths of commands built with singlechecker
+// or multichecker. The analyzers run out of process with go run, of the
+// version of their module required by gomod, the go.mod of the packages, so
+// that the results are reproducible. Analyzers of modules it doesn't require
+// need a version, e.g. "nilness@v0.24.0".
+func AnalyzerCommands(names []string, packages []string, gomod []byte) ([][]string, error) {
+	vet := []string{"go", "vet", "-json"}
+	var commands [][]string
+	for _, name := range names {
+		path, version, _ := strings.Cut(name, "@")
+		switch {
+		case vetAnalyzers[path] && version == "":
+			vet = append(vet, "-"+path)
+			continue
+		case !strings.Contains(path, "/"):
+			path = analysisPasses + path + "/cmd/" + path
+		}
+		if version != "" {
+			path += "@" + version
+		} else if !requiresPackage(gomod, path) {
+			return nil, fmt.Errorf("analyzer %v: go.mod doesn't require %v, give its version, e.g. %v@v1.2.3", name, path, name)
+		}
+		commands = append(commands, append([]string{"go", "run", path, "-json"}, packages...))
+	}
+	if len(vet) > 3 {
+		commands = append([][]string{append(vet, packages...)}, commands...)
+	}
+	return commands, nil
+}
+
+// requiresPackage reports whether the package at importPath is in the
+// module of gomod or in a module it requires.
+func requiresPackage(gomod []byte, importPath string) bool {
+	block := false
+	for _, line := range strings.Split(string(gomod), "\n") {
+		if comment := strings.Index(line, "//"); comment >= 0 {
+			line = line[:comment]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case block && fields[0] == ")":
+			block = false
+			continue
+		case fields[0] == "require" && len(fields) == 2 && fields[1] == "(":
+			block = true
+			continue
+		case fields[0] == "module" || fields[0] == "require":
+			fields = fields[1:]
+		case !block:
+			continue
+		}
+		if len(fields) > 0 {
+			module := strings.Trim(fields[0], `"`)
+			if importPath == module || strings.HasPrefix(importPath, module+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RunAnalyzers runs the analyzers on the packages in dir, see
+// AnalyzerCommands, and returns their output.
+func RunAnalyzers(dir string, names []string) ([]byte, error) {
+	var gomod []byte
+	goenv := exec.Command("go", "env", "GOMOD")
+	goenv.Dir = dir
+	if out, err := goenv.Output(); err == nil {
+		if name := strings.TrimSpace(string(out)); name != "" && name != os.DevNull {
+			gomod, _ = os.ReadFile(name)
+		}
+	}
+	commands, err := AnalyzerCommands(names, []string{"./..."}, gomod)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []byte
+	for _, args := range commands {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Dir = dir
+		var output bytes.Buffer
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		// analyzers exit with an error when they report diagnostics, after
+		// go run may print the modules it downloads
+		err := cmd.Run()
+		json := bytes.HasPrefix(output.Bytes(), []byte("{")) || bytes.Contains(output.Bytes(), []byte("\n{\n"))
+		if _, failed := err.(*exec.ExitError); err != nil && (!failed || !json) {
+			return nil, fmt.Errorf("%s: %v\n%s", strings.Join(args, " "), err, output.Bytes())
+		}
+		result = append(result, output.Bytes()...)
+	}
+	return result, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyzerCommands(t *testing.T) {
+	gomod := []byte(`module example.com/app
+
+go 1.22
+
+require golang.org/x/tools v0.24.0 // indirect
+
+require (
+	example.com/lint v1.2.0
+)
+`)
+	tests := []struct {
+		names    string
+		commands []string
+		err      bool
+	}{
+		{"printf,shadow", []string{
+			"go vet -json -printf ./...",
+			"go run golang.org/x/tools/go/analysis/passes/shadow/cmd/shadow -json ./...",
+		}, false},
+		{"nilness@v0.25.0", []string{
+			"go run golang.org/x/tools/go/analysis/passes/nilness/cmd/nilness@v0.25.0 -json ./...",
+		}, false},
+		{"example.com/lint/cmd/nopanic,example.com/app/cmd/lint", []string{
+			"go run example.com/lint/cmd/nopanic -json ./...",
+			"go run example.com/app/cmd/lint -json ./...",
+		}, false},
+		{"example.com/other/cmd/lint", nil, true},
+		{"example.com/lintx/cmd/lint", nil, true},
+	}
+	for _, test := range tests {
+		commands, err := AnalyzerCommands(strings.Split(test.names, ","), []string{"./..."}, gomod)
+		if (err != nil) != test.err {
+			t.Errorf("%v: got error %v", test.names, err)
+			continue
+		}
+		var got []string
+		for _, command := range commands {
+			got = append(got, strings.Join(command, " "))
+		}
+		if strings.Join(got, "\n") != strings.Join(test.commands, "\n") {
+			t.Errorf("%v: got\n\t%v\nwant\n\t%v", test.names, strings.Join(got, "\n\t"), strings.Join(test.commands, "\n\t"))
+		}
+	}
+
+	// without go.mod only versioned analyzers run
+	if _, err := AnalyzerCommands([]string{"nilness"}, []string{"./..."}, nil); err == nil {
+		t.Errorf("nilness without go.mod: no error")
+	}
+}
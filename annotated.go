@@ -1,64 +1,411 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
-	"io/ioutil"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 type AnnotatedFile struct {
+	Key     string `json:"key"`
 	Path    string `json:"path"`
-	AbsPath string `json:"path"`
-	Lines   []Line `json:"lines"`
+	AbsPath string `json:"abspath"`
+	Binary  bool   `json:"binary"`
+	// Generated is set for files produced by tools like go generate.
+	Generated bool `json:"generated"`
+	// Truncated is set when the file is larger than the size limit and
+	// only its first lines are included.
+	Truncated bool   `json:"truncated"`
+	Lines     []Line `json:"lines"`
+
+	// Offset is the number of lines skipped and Total the number of lines
+	// in the file, when only a page of the lines is included.
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+
+	// Orphans are notes for lines outside of the file,
+	// e.g. when the log is from an older version of it.
+	Orphans []OrphanNote `json:"orphans"`
+}
+
+// OrphanNote is a note for a line that does not exist in the file.
+type OrphanNote struct {
+	Line int `json:"line"` // 1 is the first line
+	LineNote
 }
 
 type Line struct {
 	Source string     `json:"source"`
 	Notes  []LineNote `json:"notes"`
+
+	// Truncated is the number of bytes cut from the end of Source and
+	// Omitted the number of notes left out, because of the limits.
+	Truncated int `json:"truncated,omitempty"`
+	Omitted   int `json:"omitted,omitempty"`
 }
 
 type LineNote struct {
 	Column  int    `json:"column"`
 	Message string `json:"message"`
+	Count   int    `json:"count"`
+	Tool    string `json:"tool"`
+
+	Severity Severity `json:"severity"`
+	Category string   `json:"category,omitempty"`
+	// Order is the position of the note in the logs, notes on the same line
+	// are sorted by column, tool and then Order.
+	Order int `json:"order"`
+	// Fingerprint identifies the note across edits of the file.
+	Fingerprint string `json:"fingerprint"`
+	// Span is the expression the message is about, when it could be found.
+	Span *Span `json:"span,omitempty"`
+}
+
+// Filter selects notes to include in an AnnotatedFile.
+type Filter struct {
+	Tool     string // empty includes all tools
+	Severity Severity
+
+	// GeneratedPositions shows the notes of original sources at the lines
+	// of a generated file with //line directives.
+	GeneratedPositions bool
+}
+
+// Match reports whether note passes the filter.
+func (filter Filter) Match(note *Note) bool {
+	return (filter.Tool == "" || note.Tool == filter.Tool) && note.Severity >= filter.Severity
+}
+
+// errNotFound is returned for paths that are not in the index.
+var errNotFound = errors.New("not found")
+
+// LoadAnnotatedFile loads the source of path with notes matching filter.
+// Reading the source stops when ctx is done.
+func (index *Index) LoadAnnotatedFile(ctx context.Context, path string, filter Filter) (*AnnotatedFile, error) {
+	snapshot, ok := index.snapshotFile(path, filter)
+	if !ok {
+		return nil, errNotFound
+	}
+	return snapshot.Load(ctx)
+}
+
+// fileSnapshot is an indexed file with its notes, copied from the index so
+// that the source can be read and annotated without holding the lock of the
+// server, reading from a network file system may take long.
+type fileSnapshot struct {
+	key       string
+	path      string
+	absPath   string
+	generated bool
+	source    []byte
+	notes     []Note
+
+	// generation and modified are those of the index when it was copied.
+	generation int
+	modified   time.Time
+
+	sources fs.FS
+	limits  Limits
+	filter  Filter
 }
 
-func (index *Index) LoadAnnotatedFile(path string) (*AnnotatedFile, error) {
-	info, ok := index.Files[path]
+// snapshotFile copies the file at path with its notes for filter,
+// returns false when it is not in the index.
+func (index *Index) snapshotFile(path string, filter Filter) (*fileSnapshot, bool) {
+	info, ok := index.Lookup(path)
 	if !ok {
-		return nil, errors.New("not found")
+		return nil, false
+	}
+	return index.snapshotOf(info, filter), true
+}
+
+func (index *Index) snapshotOf(info *File, filter Filter) *fileSnapshot {
+	notes := info.Notes
+	if filter.GeneratedPositions {
+		notes = index.GeneratedNotes(info)
 	}
+	return &fileSnapshot{
+		key:       info.Key,
+		path:      info.Path,
+		absPath:   info.AbsPath,
+		generated: info.Generated,
+		source:    info.Source,
+		// counts of notes are updated in place when logs are parsed
+		notes: append([]Note(nil), notes...),
+
+		generation: index.Generation,
+		modified:   index.Modified,
 
-	data, err := ioutil.ReadFile(info.AbsPath)
+		sources: index.sources(),
+		limits:  index.Limits,
+		filter:  filter,
+	}
+}
+
+// Load reads the source of the file and annotates its lines.
+// Reading the source stops when ctx is done.
+func (snapshot *fileSnapshot) Load(ctx context.Context) (*AnnotatedFile, error) {
+	limits, filter := snapshot.limits, snapshot.filter
+	data, truncated, err := snapshot.loadSource(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	file := &AnnotatedFile{}
-	file.Path = info.Path
-	file.AbsPath = info.AbsPath
+	file.Key = snapshot.key
+	file.Path = snapshot.path
+	file.AbsPath = snapshot.absPath
+	file.Generated = snapshot.generated
+	file.Truncated = truncated
+
+	if IsBinary(data) {
+		file.Binary = true
+		file.Lines = []Line{}
+		file.Orphans = []OrphanNote{}
+		return file, nil
+	}
+
+	notes := snapshot.notes
+	funcs := snapshot.funcs(data, truncated)
 
+	file.Orphans = []OrphanNote{}
 	noteidx := 0
-	sourceLines := strings.Split(string(data), "\n")
-	for i, sourceLine := range sourceLines {
+	for noteidx < len(notes) && notes[noteidx].Line < 0 {
+		x := &notes[noteidx]
+		noteidx++
+		if filter.Match(x) {
+			file.Orphans = append(file.Orphans, OrphanNote{x.Line + 1, newLineNote(x, Fingerprint(file.Path, funcs, x))})
+		}
+	}
+
+	for i, sourceLine := range SourceLines(data) {
 		line := Line{}
-		line.Source = sourceLine
+		line.Source, line.Truncated = limits.truncateLine(sourceLine)
 		line.Notes = []LineNote{}
 
-		for noteidx < len(info.Notes) && i > info.Notes[noteidx].Line {
+		for noteidx < len(notes) && i == notes[noteidx].Line {
+			x := &notes[noteidx]
 			noteidx++
-		}
-		for noteidx < len(info.Notes) && i == info.Notes[noteidx].Line {
-			x := info.Notes[noteidx]
-			note := LineNote{
-				Column:  x.Column,
-				Message: string(x.Message),
+			if filter.Match(x) {
+				if limits.LineNotes > 0 && len(line.Notes) >= limits.LineNotes {
+					line.Omitted++
+					continue
+				}
+				note := newLineNote(x, Fingerprint(file.Path, funcs, x))
+				note.Span = x.Span
+				if note.Span == nil {
+					note.Span = FindSpan(sourceLine, x.Column, x.Message)
+				}
+				if note.Span != nil && note.Span.End > len(line.Source) {
+					note.Span = nil
+				}
+				line.Notes = append(line.Notes, note)
 			}
-			line.Notes = append(line.Notes, note)
-			noteidx++
 		}
 
 		file.Lines = append(file.Lines, line)
 	}
+	file.Total = len(file.Lines)
+
+	for ; noteidx < len(notes); noteidx++ {
+		x := &notes[noteidx]
+		if filter.Match(x) {
+			file.Orphans = append(file.Orphans, OrphanNote{x.Line + 1, newLineNote(x, Fingerprint(file.Path, funcs, x))})
+		}
+	}
 
 	return file, nil
 }
+
+// loadSource reads the source of the file within the file size limit and
+// reports whether it was truncated.
+func (snapshot *fileSnapshot) loadSource(ctx context.Context) ([]byte, bool, error) {
+	data := snapshot.source
+	if data == nil {
+		max := int64(-1)
+		if snapshot.limits.FileSize > 0 {
+			max = snapshot.limits.FileSize + 1
+		}
+		var err error
+		data, err = ReadFileContext(ctx, snapshot.sources, SourceName(snapshot.absPath), max)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	data, truncated := snapshot.limits.truncateFile(data)
+	return data, truncated, nil
+}
+
+// funcs returns the functions of a Go file for Fingerprint like
+// Index.fileFuncs, data is the loaded source.
+func (snapshot *fileSnapshot) funcs(data []byte, truncated bool) []funcRange {
+	if snapshot.source != nil || filepath.Ext(snapshot.absPath) != ".go" {
+		return nil
+	}
+	if truncated {
+		var err error
+		data, err = fs.ReadFile(snapshot.sources, SourceName(snapshot.absPath))
+		if err != nil {
+			return nil
+		}
+	}
+	_, funcs := parseFuncs(snapshot.absPath, data)
+	return funcs
+}
+
+// SourceLines splits the source of a file into the lines shown.
+func SourceLines(data []byte) []string {
+	source := strings.ToValidUTF8(string(data), "\uFFFD")
+	source = strings.Replace(source, "\r\n", "\n", -1)
+	source = strings.TrimSuffix(source, "\n")
+	return strings.Split(source, "\n")
+}
+
+// Page restricts the lines of the file to at most limit lines starting
+// at offset, a negative limit includes all lines after offset.
+func (file *AnnotatedFile) Page(offset, limit int) {
+	if offset > len(file.Lines) {
+		offset = len(file.Lines)
+	}
+	end := len(file.Lines)
+	if limit >= 0 && offset+limit < end {
+		end = offset + limit
+	}
+	file.Lines = file.Lines[offset:end]
+	file.Offset = offset
+}
+
+func newLineNote(note *Note, fingerprint string) LineNote {
+	return LineNote{
+		Column:  note.Column,
+		Message: string(note.Message),
+		Count:   note.Count,
+		Tool:    note.Tool,
+
+		Severity: note.Severity,
+		Category: note.Category,
+		Order:    note.Order,
+
+		Fingerprint: fingerprint,
+	}
+}
+
+// IsBinary reports whether data looks like a binary file,
+// using the same heuristic as git: a NUL byte in the first 8000 bytes.
+func IsBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// IsGenerated reports whether data is a generated file, recognized by the
+// standard comment before the package clause:
+//
+//	// Code generated by stringer; DO NOT EDIT.
+func IsGenerated(data []byte) bool {
+	for _, line := range SplitLines(data) {
+		if bytes.HasPrefix(line, []byte("package ")) {
+			break
+		}
+		if bytes.HasPrefix(line, []byte("// Code generated ")) && bytes.HasSuffix(line, []byte(" DO NOT EDIT.")) {
+			return true
+		}
+	}
+	return false
+}
+
+// AnnotatedDir is all annotated files directly inside a directory.
+type AnnotatedDir struct {
+	Path  string           `json:"path"`
+	Files []*AnnotatedFile `json:"files"`
+}
+
+// LoadAnnotatedDir loads all files in dir with notes matching filter,
+// files that cannot be read are skipped.
+func (index *Index) LoadAnnotatedDir(ctx context.Context, dir string, filter Filter) (*AnnotatedDir, error) {
+	snapshots, ok := index.snapshotDir(dir, filter)
+	if !ok {
+		return nil, errNotFound
+	}
+	return loadAnnotatedDir(ctx, dir, snapshots)
+}
+
+// snapshotDir copies the files in dir with their notes for filter,
+// returns false when there are none.
+func (index *Index) snapshotDir(dir string, filter Filter) ([]*fileSnapshot, bool) {
+	var snapshots []*fileSnapshot
+	for _, file := range index.FilesIn(dir) {
+		snapshots = append(snapshots, index.snapshotOf(file, filter))
+	}
+	return snapshots, len(snapshots) > 0
+}
+
+// loadAnnotatedDir loads the copied files of dir, see LoadAnnotatedDir.
+func loadAnnotatedDir(ctx context.Context, dir string, snapshots []*fileSnapshot) (*AnnotatedDir, error) {
+	annotated := &AnnotatedDir{Path: dir}
+	for _, snapshot := range snapshots {
+		loaded, err := snapshot.Load(ctx)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			continue
+		}
+		annotated.Files = append(annotated.Files, loaded)
+	}
+	return annotated, nil
+}
+
+// LineInfo is a single line with its notes and surrounding source.
+type LineInfo struct {
+	Path    string        `json:"path"`
+	AbsPath string        `json:"abspath"`
+	Line    int           `json:"line"`
+	Notes   []LineNote    `json:"notes"`
+	Context []ContextLine `json:"context"`
+}
+
+// ContextLine is a source line near the requested one.
+type ContextLine struct {
+	Line   int    `json:"line"`
+	Source string `json:"source"`
+}
+
+// LineContext returns line (1 is the first line) with context lines
+// before and after it, returns false when line is outside of the file.
+func (file *AnnotatedFile) LineContext(line, context int) (*LineInfo, bool) {
+	if line < 1 || line > len(file.Lines) {
+		return nil, false
+	}
+
+	info := &LineInfo{
+		Path:    file.Path,
+		AbsPath: file.AbsPath,
+		Line:    line,
+		Notes:   file.Lines[line-1].Notes,
+		Context: []ContextLine{},
+	}
+
+	from, to := line-context, line+context
+	if from < 1 {
+		from = 1
+	}
+	if to > len(file.Lines) {
+		to = len(file.Lines)
+	}
+	for i := from; i <= to; i++ {
+		info.Context = append(info.Context, ContextLine{
+			Line:   i,
+			Source: file.Lines[i-1].Source,
+		})
+	}
+	return info, true
+}
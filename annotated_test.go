@@ -0,0 +1,26 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSourceLines(t *testing.T) {
+	tests := []struct {
+		data  string
+		lines []string
+	}{
+		{"", []string{""}},
+		{"package a", []string{"package a"}},
+		{"package a\n\n", []string{"package a", ""}},
+		{"package a\r\n\r\nfunc f() {}\r\n", []string{"package a", "", "func f() {}"}},
+		{"a\r\nb\nc\r\nd", []string{"a", "b", "c", "d"}},
+		// the compiler counts lines by \n, a lone \r is part of the line
+		{"a\rb\nc", []string{"a\rb", "c"}},
+	}
+	for _, test := range tests {
+		if lines := SourceLines([]byte(test.data)); !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("SourceLines(%q) = %q, want %q", test.data, lines, test.lines)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+//go:embed assets
+var assets embed.FS
+
+// LoadTemplate parses the UI from the embedded assets, files in dir,
+// when it is not empty, override the embedded files with the same name.
+func LoadTemplate(dir string) (*template.Template, error) {
+	t := template.New("").Funcs(template.FuncMap{
+		"mul":   func(a, b int) int { return a * b },
+		"bytes": FormatBytes,
+		"dir":   filepath.Dir,
+	})
+
+	entries, err := fs.ReadDir(assets, "assets")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		data, err := fs.ReadFile(assets, "assets/"+name)
+		if err != nil {
+			return nil, err
+		}
+
+		if dir != "" {
+			override, err := os.ReadFile(filepath.Join(dir, name))
+			if err == nil {
+				data = override
+			} else if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+
+		if _, err := t.New(name).Parse(string(data)); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// ParseTemplateFile parses a user supplied template file as template name,
+// replacing the embedded one.
+func ParseTemplateFile(t *template.Template, name string, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	_, err = t.New(name).Parse(string(data))
+	return err
+}
+
+// FormatBytes formats a byte count using binary units, e.g. "1.5 MiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,886 @@
+var pending = null;
+var scrollToLine = 0;
+var severityRank = {info: 0, warning: 1, error: 2};
+var live = {{ .Live }};
+var embedded = {{ .Embedded }};
+var stats = [
+	{{- range .Stats }}
+	{name: {{.Name}}, good: {{.Good}}, bad: {{.Bad}}},
+	{{- end }}
+];
+function fileSelected() {
+	var el = document.getElementById("file");
+	var dir = document.getElementById("dir");
+	var tool = document.getElementById("tool");
+	var severity = document.getElementById("severity");
+	var positions = document.getElementById("positions");
+	var filter = "&tool=" + encodeURIComponent(tool.value) +
+		"&severity=" + encodeURIComponent(severity.value) +
+		"&positions=" + encodeURIComponent(positions.value);
+
+	if(dir.value != ""){
+		load("/dir?path=" + encodeURIComponent(dir.value) + filter, dir => showFiles(dir.files, true));
+	} else if(el.value != ""){
+		load("/file?path=" + encodeURIComponent(el.value) + filter, file => showFiles([file], false));
+		if(search.query != "" && search.path != el.value) runSearch();
+		if(live) watch("/api/v1/watch?path=" + encodeURIComponent(el.value) + filter);
+	}
+}
+
+// watch reloads the file when new annotations are pushed while logs
+// are still being parsed.
+var watched = null;
+var reloadTimer = null;
+function watch(url) {
+	if(watched && watched.url == url) return;
+	if(watched) watched.socket.close();
+
+	var protocol = location.protocol == "https:" ? "wss://" : "ws://";
+	var socket = new WebSocket(protocol + location.host + url);
+	socket.onmessage = () => {
+		clearTimeout(reloadTimer);
+		reloadTimer = setTimeout(fileSelected, 100);
+	};
+	watched = {url: url, socket: socket};
+}
+
+function load(url, callback) {
+	if(embedded){
+		var data = loadEmbedded(url);
+		if(data) callback(data);
+		return;
+	}
+	if(pending){
+		pending.abort();
+	}
+	pending = fetch(url)
+		.then(function(response){
+			pending = null;
+			if(response.ok){
+				response.json().then(callback);
+			}
+		})
+}
+
+// loadEmbedded answers /file and /dir requests from the data embedded
+// in an exported report, which is opened without the server.
+function loadEmbedded(url) {
+	var params = new URLSearchParams(url.substr(url.indexOf("?")));
+	var tool = params.get("tool") || "";
+	var severity = severityRank[params.get("severity") || "info"];
+	var match = note => (tool == "" || note.tool == tool) && severityRank[note.severity] >= severity;
+	var filter = file => Object.assign({}, file, {
+		lines: file.lines.map(line => Object.assign({}, line, {notes: line.notes.filter(match)})),
+		orphans: file.orphans.filter(match)
+	});
+
+	var path = params.get("path");
+	if(url.startsWith("/dir")){
+		var keys = embedded.dirs[path];
+		return keys ? {path: path, files: keys.map(key => filter(embedded.files[key]))} : null;
+	}
+	var file = embedded.files[path];
+	return file ? filter(file) : null;
+}
+
+function fileChanged() {
+	document.getElementById("dir").value = "";
+	stateChanged();
+}
+
+function openFile(path, line) {
+	document.getElementById("file").value = path;
+	document.getElementById("dir").value = "";
+	scrollToLine = line;
+	currentLine = line;
+	saveState(true);
+	fileSelected();
+}
+
+// The order of the file list is remembered by the browser, the server
+// renders it in the default order.
+var defaultOrder = {{ .Order }};
+function orderChanged() {
+	var order = document.getElementById("order").value;
+	try { localStorage.setItem("order", order); } catch(e) {}
+	sortFiles(order);
+}
+
+function sortFiles(order) {
+	var el = document.getElementById("file");
+	var options = Array.from(el.options);
+	var key = option => parseInt(option.dataset[order] || "0");
+	// byte order like the server, so that directories stay together
+	var byPath = (a, b) => (a.dataset.path > b.dataset.path) - (a.dataset.path < b.dataset.path);
+	options.sort((a, b) => order == "path" ? byPath(a, b) : key(b) - key(a) || byPath(a, b));
+
+	// files sorted by path are grouped by directory, like the server does
+	var value = el.value;
+	el.textContent = "";
+	var group = null;
+	options.forEach(option => {
+		if(order != "path"){
+			el.appendChild(option);
+			return;
+		}
+		if(!group || group.label != option.dataset.dir){
+			group = document.createElement("optgroup");
+			group.label = option.dataset.dir;
+			el.appendChild(group);
+		}
+		group.appendChild(option);
+	});
+	el.value = value;
+}
+
+function loadOrder() {
+	var order = defaultOrder;
+	try { order = localStorage.getItem("order") || order; } catch(e) {}
+	document.getElementById("order").value = order;
+	if(order != defaultOrder) sortFiles(order);
+}
+
+// openDemo opens a file of the sample project of -demo by its path.
+function openDemo(path, line) {
+	var option = Array.from(document.getElementById("file").options).find(option => option.dataset.path == path);
+	if(option) openFile(option.value, line);
+}
+
+// UI state is kept in the URL so that it can be bookmarked
+// and the browser history works.
+var currentLine = 0;
+
+function stateChanged() {
+	currentLine = 0;
+	saveState(true);
+	fileSelected();
+}
+
+function saveState(push) {
+	var params = new URLSearchParams();
+	["file", "dir", "tool", "severity", "positions"].forEach(id => {
+		var value = document.getElementById(id).value;
+		if(value != "") params.set(id, value);
+	});
+	var url = "?" + params.toString() + (currentLine > 0 ? "#L" + currentLine : "");
+	if(push){
+		history.pushState(null, "", url);
+	} else {
+		history.replaceState(null, "", url);
+	}
+}
+
+function loadState() {
+	var params = new URLSearchParams(location.search);
+	["file", "dir", "tool", "severity", "positions"].forEach(id => {
+		if(params.has(id)) document.getElementById(id).value = params.get(id);
+	});
+	var match = location.hash.match(/^#L(\d+)$/);
+	currentLine = match ? parseInt(match[1]) : 0;
+	scrollToLine = currentLine;
+}
+
+window.addEventListener("popstate", () => {
+	loadState();
+	fileSelected();
+});
+
+var scrollTimer = null;
+window.addEventListener("scroll", () => {
+	clearTimeout(scrollTimer);
+	scrollTimer = setTimeout(() => {
+		var el = document.elementFromPoint(1, 1);
+		var lineel = el && el.closest("tr.line");
+		if(lineel) highlightSidebar(lineel.id);
+		if(lineel && lineel.id[0] == "L"){
+			currentLine = parseInt(lineel.id.substr(1));
+			saveState(false);
+		}
+	}, 200);
+});
+
+// legendFilter shows only the notes of a category of the legend,
+// e.g. {stat: 1, kind: "bad"} for the heap escapes.
+var legendFilter = null;
+var shown = null;
+
+// showFiles renders the files, with a header for each file when
+// several files are shown together.
+function showFiles(files, headers) {
+	shown = {files: files, headers: headers};
+	renderLegend(files);
+	if(legendFilter){
+		var stat = stats[legendFilter.stat];
+		var keywords = stat[legendFilter.kind];
+		var match = note => keywords.some(keyword => note.message.indexOf(keyword) >= 0);
+		files = files.map(file => Object.assign({}, file, {
+			lines: file.lines.map(line => Object.assign({}, line, {notes: line.notes.filter(match)})),
+			orphans: file.orphans.filter(match)
+		}));
+	}
+
+	var notice = document.getElementById("notice");
+	var binary = !headers && files[0].binary;
+	var generated = !headers && files[0].generated;
+	var truncated = !headers && files[0].truncated;
+	notice.hidden = !binary && !generated && !truncated;
+	notice.innerText = binary ? "Binary file, source not shown." :
+		generated ? "Generated file, fix the annotations in its generator." :
+		truncated ? "Large file, only the first " + files[0].total + " lines are shown." : "";
+
+	var columns = 3 + stats.length;
+	var fragment = document.createDocumentFragment();
+	files.forEach((file, fileIndex) => {
+		var prefix = headers ? "F" + fileIndex + "-" : "";
+		if(headers){
+			var link = h("a", "", file.path);
+			link.href = "#";
+			link.onclick = () => { openFile(file.key, 0); return false; };
+			var header = h("th", "", [link]);
+			if(file.generated){
+				header.appendChild(h("span", "badge generated", "generated"));
+			}
+			header.scope = "rowgroup";
+			header.colSpan = columns;
+			if(file.binary){
+				header.appendChild(document.createTextNode(" (binary file, source not shown)"));
+			}
+			if(file.truncated){
+				header.appendChild(document.createTextNode(" (large file, only the first " + file.total + " lines are shown)"));
+			}
+			fragment.appendChild(h("tr", "file-header", [header]));
+		}
+		renderLines(fragment, file, prefix, columns);
+		renderOrphans(fragment, file, columns);
+	});
+
+	var lines = document.getElementById("lines");
+	lines.innerText = "";
+	lines.appendChild(fragment);
+	renderSidebar(files, headers);
+
+	if(scrollToLine > 0){
+		var lineel = document.getElementById("L" + scrollToLine);
+		if(lineel) lineel.scrollIntoView();
+		scrollToLine = 0;
+	}
+}
+
+// search are the matches of the search box in the shown file. The server
+// searches, so that the browser needn't hold the lines of huge files.
+var search = {query: "", path: "", matches: [], byLine: {}, current: -1};
+var searchTimer = null;
+
+function searchChanged() {
+	clearTimeout(searchTimer);
+	searchTimer = setTimeout(runSearch, 300);
+}
+
+function runSearch() {
+	var query = document.getElementById("search").value;
+	var path = document.getElementById("dir").value == "" ? document.getElementById("file").value : "";
+	var found = result => {
+		search = {query: query, path: path, matches: result.matches, byLine: {}, current: -1, truncated: result.truncated};
+		search.matches.forEach((match, i) => {
+			(search.byLine[match.line] = search.byLine[match.line] || []).push(Object.assign({index: i}, match));
+		});
+		if(shown) showFiles(shown.files, shown.headers);
+		var next = search.matches.findIndex(match => match.line >= currentLine);
+		searchStep(0, next < 0 ? 0 : next);
+	};
+	if(query == "" || path == ""){
+		found({matches: []});
+		return;
+	}
+	if(embedded){
+		try {
+			found(searchEmbedded(path, new RegExp(query, "g")));
+		} catch(e) {
+			document.getElementById("search-status").innerText = "invalid regexp";
+		}
+		return;
+	}
+	fetch("/api/v1/search?path=" + encodeURIComponent(path) + "&q=" + encodeURIComponent(query))
+		.then(response => {
+			if(response.ok){
+				response.json().then(found);
+			} else {
+				document.getElementById("search-status").innerText = "invalid regexp";
+			}
+		});
+}
+
+// searchEmbedded searches the source of an exported report.
+function searchEmbedded(path, re) {
+	var file = embedded.files[path];
+	var matches = [];
+	(file ? file.lines : []).forEach((line, i) => {
+		for(var m of line.source.matchAll(re)){
+			if(m[0].length > 0) matches.push({line: i + 1, start: m.index, end: m.index + m[0].length});
+		}
+	});
+	return {matches: matches, truncated: false};
+}
+
+// searchStep moves to the next (1) or previous (-1) match, or to the
+// match at index.
+function searchStep(step, index) {
+	var status = document.getElementById("search-status");
+	var count = search.matches.length;
+	if(count == 0){
+		status.innerText = search.query == "" ? "" : "no matches";
+		return;
+	}
+	var previous = document.getElementById("M" + search.current);
+	if(previous) previous.classList.remove("current");
+	search.current = index !== undefined ? index : (search.current + step + count) % count;
+	var match = search.matches[search.current];
+	status.innerText = (search.current + 1) + "/" + count + (search.truncated ? "+" : "");
+	var mark = document.getElementById("M" + search.current);
+	if(mark){
+		mark.classList.add("current");
+		mark.scrollIntoView({block: "center"});
+	}
+	currentLine = match.line;
+	saveState(false);
+}
+
+function searchKey(ev) {
+	if(ev.key == "Enter"){
+		searchStep(ev.shiftKey ? -1 : 1);
+		ev.preventDefault();
+	}
+}
+
+// renderLegend shows the colors of the categories and severities with their
+// counts in the files, a category can be clicked to show only its notes.
+function renderLegend(files) {
+	var counts = stats.map(() => ({good: 0, bad: 0}));
+	var severities = {info: 0, warning: 0, error: 0};
+	files.forEach(file => file.lines.forEach(line => line.notes.forEach(note => {
+		severities[note.severity]++;
+		stats.forEach((stat, i) => {
+			["good", "bad"].forEach(kind => {
+				if(stat[kind].some(keyword => note.message.indexOf(keyword) >= 0)) counts[i][kind]++;
+			});
+		});
+	})));
+
+	var legend = document.getElementById("legend");
+	legend.innerText = "";
+	stats.forEach((stat, i) => {
+		var item = h("span", "legend-stat", [stat.name + " "]);
+		["good", "bad"].forEach(kind => {
+			var active = legendFilter && legendFilter.stat == i && legendFilter.kind == kind;
+			var button = h("button", kind + (active ? " active" : ""), counts[i][kind]);
+			button.title = (kind == "good" ? "Optimized: " : "Not optimized: ") + stat[kind].join(", ");
+			button.setAttribute("aria-pressed", active ? "true" : "false");
+			button.setAttribute("aria-label", stat.name + ": " + counts[i][kind] + " " + kind + (active ? ", shown only" : ""));
+			button.onclick = () => {
+				legendFilter = active ? null : {stat: i, kind: kind};
+				showFiles(shown.files, shown.headers);
+			};
+			item.appendChild(button);
+			if(kind == "good") item.appendChild(document.createTextNode("/"));
+		});
+		legend.appendChild(item);
+	});
+	Object.keys(severities).forEach(severity => {
+		legend.appendChild(h("span", "legend-severity severity-" + severity, severity + " " + severities[severity]));
+	});
+}
+
+// renderSidebar lists the annotated lines with their most severe note,
+// as an index into long files.
+function renderSidebar(files, headers) {
+	var list = document.getElementById("sidebar-lines");
+	list.innerText = "";
+	files.forEach((file, fileIndex) => {
+		var prefix = headers ? "F" + fileIndex + "-" : "";
+		if(headers && file.lines.some(line => line.notes.length > 0)){
+			list.appendChild(h("li", "sidebar-file", file.path));
+		}
+		file.lines.forEach((line, index) => {
+			if(line.notes.length == 0) return;
+			var top = line.notes.reduce((a, b) => severityRank[b.severity] > severityRank[a.severity] ? b : a);
+			var id = prefix + "L" + (index + 1);
+			var link = h("a", "severity-" + top.severity, [h("span", "number", index + 1), " " + top.message]);
+			link.href = "#" + id;
+			link.title = line.notes.map(noteTitle).join("\n");
+			link.onclick = () => {
+				var lineel = document.getElementById(id);
+				if(lineel) lineel.scrollIntoView();
+				return false;
+			};
+			var item = h("li", "", [link]);
+			item.id = "S" + id;
+			list.appendChild(item);
+		});
+	});
+	document.body.classList.toggle("with-sidebar", list.children.length > 0);
+}
+
+// highlightSidebar marks the entry of the last annotated line at or above
+// the line with id, which is at the top of the window.
+var sidebarActive = null;
+function highlightSidebar(id) {
+	if(sidebarActive) sidebarActive.classList.remove("active");
+	sidebarActive = null;
+	var match = id.match(/^(.*L)(\d+)$/);
+	if(!match) return;
+	for(var number = parseInt(match[2]); number > 0 && !sidebarActive; number--){
+		sidebarActive = document.getElementById("S" + match[1] + number);
+	}
+	if(sidebarActive){
+		sidebarActive.classList.add("active");
+		sidebarActive.scrollIntoView({block: "nearest"});
+	}
+}
+
+function renderLines(fragment, file, prefix, columns) {
+	file.lines.forEach((line, index) => {
+		var number = index + 1;
+		var lineel = h("tr", "line");
+		lineel.id = prefix + "L" + number;
+		var numberel = h("th", "number", number);
+		numberel.scope = "row";
+		lineel.appendChild(numberel);
+
+		var source = h("td", "source");
+		var matches = prefix == "" && search.path == file.key ? search.byLine[number] || [] : [];
+		var spans = matches.map(match => ({span: match, search: match.index})).concat(line.notes.filter(note => note.span));
+		var p = 0;
+		var noteIndex = 0;
+		while(noteIndex < line.notes.length){
+			var note = line.notes[noteIndex];
+			if(note.column < 0){
+				noteIndex++;
+				continue;
+			}
+			appendSource(source, line.source, p, note.column, spans);
+			p = note.column;
+			noteIndex++;
+
+			var tip = document.createElement("span");
+			tip.className = "tip";
+			tip.title = noteTitle(note);
+			tip.innerText = " ";
+			while((noteIndex < line.notes.length) && (line.notes[noteIndex].column == p)){
+				tip.title += "\n" + noteTitle(line.notes[noteIndex]);
+				noteIndex++;
+			}
+			tip.setAttribute("role", "img");
+			tip.setAttribute("aria-label", tip.title);
+			source.appendChild(tip);
+		}
+		appendSource(source, line.source, p, line.source.length, spans);
+		if(line.truncated){
+			source.appendChild(h("span", "truncated", "\u2026 " + line.truncated + " more bytes"));
+		}
+		lineel.appendChild(source);
+
+		var notesel = null;
+		if(line.notes.length > 0){
+			var top = line.notes.reduce((a, b) => severityRank[b.severity] > severityRank[a.severity] ? b : a);
+
+			// full text of the annotations is shown in a row below the line
+			notesel = h("tr", "notes");
+			notesel.id = prefix + "N" + number;
+			notesel.hidden = true;
+			notesel.dataset.path = file.key;
+			notesel.dataset.line = number;
+			notesel.appendChild(h("td", "number"));
+			var list = h("ul", "", line.notes.map(note => h("li", "severity-" + note.severity, [
+				h("span", "badge", note.tool), " " + note.severity + ": " + noteText(note),
+				note.category ? " [" + note.category + "]" : "",
+				explainButton(note)
+			])));
+			if(line.omitted){
+				list.appendChild(h("li", "omitted", "\u2026 " + line.omitted + " more annotations"));
+			}
+			var cell = h("td", "", [list]);
+			cell.colSpan = columns - 1;
+			notesel.appendChild(cell);
+
+			var button = h("button", "", [h("span", "badge", top.tool), " ", noteText(top)]);
+			button.setAttribute("aria-expanded", "false");
+			button.setAttribute("aria-controls", notesel.id);
+			button.setAttribute("aria-label", "Line " + number + ": " + line.notes.length + " annotations, " + top.severity + ": " + noteText(top));
+			button.onclick = toggleNotes;
+			lineel.appendChild(h("td", "info severity-" + top.severity, [button]));
+		} else {
+			lineel.appendChild(h("td", "info"));
+		}
+
+		stats.forEach((stat, i) => {
+			var goodCount = 0;
+			var badCount = 0;
+
+			line.notes.forEach(note => {
+				stat.good.forEach(keyword => {
+					if(note.message.indexOf(keyword) >= 0){
+						goodCount++;
+					}
+				});
+				stat.bad.forEach(keyword => {
+					if(note.message.indexOf(keyword) >= 0){
+						badCount++;
+					}
+				});
+			})
+
+			if(goodCount + badCount > 0){
+				var goodel = h("span", "good", goodCount);
+				if(goodCount > 0) goodel.className += " active";
+				goodel.title = stat.good.join("\n");
+
+				var badel = h("span", "bad", badCount);
+				if(badCount > 0) badel.className += " active";
+				badel.title = stat.bad.join("\n");
+
+				var el = h("td", "tag active tag-" + i, [
+					goodel, "/", badel
+				]);
+				el.setAttribute("aria-label", stat.name + ": " + goodCount + " good, " + badCount + " bad");
+				lineel.appendChild(el);
+			} else {
+				lineel.appendChild(h("td", "tag tag-" + i));
+			}
+		});
+
+		fragment.appendChild(lineel);
+		if(notesel){
+			fragment.appendChild(notesel);
+		}
+	});
+}
+
+// loadDevirtualization lists interface method calls grouped by interface,
+// it is loaded on demand because the packages have to be type-checked.
+function loadDevirtualization(details){
+	var el = document.getElementById("devirtualization");
+	if(!details.open || el.dataset.loaded) return;
+	el.dataset.loaded = "1";
+	fetch("/api/v1/devirtualization")
+		.then(response => response.json())
+		.then(groups => {
+			el.innerText = groups.length == 0 ? "No interface calls found." : "";
+			groups.forEach(group => {
+				var calls = h("ul", "", group.calls.map(call => {
+					var link = h("a", "", call.path + ":" + call.line);
+					link.href = "#";
+					link.onclick = () => { openFile(call.key, call.line); return false; };
+					return h("li", call.devirtualized ? "devirtualized" : "", [
+						link, " ." + call.method + (call.devirtualized ? " (devirtualized)" : "")
+					]);
+				}));
+				el.appendChild(h("details", "", [
+					h("summary", "", group.interface + ": " + (group.calls.length - group.devirtualized) +
+						" of " + group.calls.length + " calls not devirtualized"),
+					calls
+				]));
+			});
+		});
+}
+
+// loadEscapeTypes groups the heap escapes by the type of the escaping
+// value, the types escaping most often are candidates for pooling.
+function loadEscapeTypes(details){
+	var el = document.getElementById("escape-types");
+	if(!details.open || el.dataset.loaded) return;
+	el.dataset.loaded = "1";
+	fetch("/api/v1/escapes-by-type")
+		.then(response => response.json())
+		.then(groups => {
+			el.innerText = groups.length == 0 ? "No heap escapes found." : "";
+			groups.forEach(group => {
+				var sites = h("ul", "", group.sites.map(site => {
+					var link = h("a", "", site.path + ":" + site.line);
+					link.href = "#";
+					link.onclick = () => { openFile(site.key, site.line); return false; };
+					return h("li", "", [link, " " + site.message]);
+				}));
+				el.appendChild(h("details", "", [
+					h("summary", "", [h("code", "", group.type), ": " + group.sites.length + " escapes"]),
+					sites
+				]));
+			});
+		});
+}
+
+// loadPoolCandidates lists the types of escaping values that might be
+// worth pooling, ranked by allocated bytes and the weight of their sites.
+function loadPoolCandidates(details){
+	var el = document.getElementById("pool-candidates");
+	if(!details.open || el.dataset.loaded) return;
+	el.dataset.loaded = "1";
+	fetch("/api/v1/pool-candidates")
+		.then(response => response.json())
+		.then(candidates => {
+			el.innerText = candidates.length == 0 ? "No candidates found." : "";
+			el.appendChild(h("ol", "", candidates.map(candidate => {
+				var sites = h("ul", "", candidate.sites.map(site => {
+					var link = h("a", "", site.path + ":" + site.line);
+					link.href = "#";
+					link.onclick = () => { openFile(site.key, site.line); return false; };
+					return h("li", "", [link, " " + site.message]);
+				}));
+				return h("li", "", [h("details", "", [
+					h("summary", "", [h("code", "", candidate.type), ": " + candidate.sites.length + " escape sites" +
+						(candidate.bytes ? ", " + formatBytes(candidate.bytes) + " allocated" : "")]),
+					sites
+				])]);
+			})));
+		});
+}
+
+// formatBytes formats n like FormatBytes on the server, e.g. "1.5 MiB".
+function formatBytes(n){
+	if(n < 1024) return n + " B";
+	var exp = 0;
+	for(n /= 1024; n >= 1024; n /= 1024) exp++;
+	return n.toFixed(1) + " " + "KMGTPE"[exp] + "iB";
+}
+
+var inlining = [];
+
+// loadInlining fetches the inlining status of the exported functions,
+// which are listed per package to audit the inlinability of the API.
+function loadInlining(details){
+	var el = document.getElementById("inlining");
+	if(!details.open || el.dataset.loaded) return;
+	el.dataset.loaded = "1";
+	fetch("/api/v1/inlining")
+		.then(response => response.json())
+		.then(statuses => {
+			inlining = statuses;
+			var select = document.getElementById("inlining-package");
+			var dirs = [];
+			statuses.forEach(status => {
+				if(dirs.indexOf(status.dir) < 0){
+					dirs.push(status.dir);
+					var option = h("option", "", status.dir + " (" + status.package + ")");
+					option.value = status.dir;
+					select.appendChild(option);
+				}
+			});
+			renderInlining();
+		});
+}
+
+function renderInlining(){
+	var el = document.getElementById("inlining");
+	var dir = document.getElementById("inlining-package").value;
+	var statuses = inlining.filter(status => dir == "" || status.dir == dir);
+	el.innerText = statuses.length == 0 ? "No exported functions found." : "";
+	if(statuses.length == 0) return;
+
+	var rows = statuses.map(status => {
+		var link = h("a", "", status.func);
+		link.href = "#";
+		link.onclick = () => { openFile(status.key, status.line); return false; };
+		return h("tr", status.status.replace(" ", "-"), [
+			h("td", "", status.dir), h("td", "", [link]), h("td", "", status.status),
+			h("td", "", status.cost ? status.cost : ""), h("td", "", status.reason || "")
+		]);
+	});
+	el.appendChild(h("table", "", [
+		h("thead", "", [h("tr", "", ["Package", "Function", "Status", "Cost", "Reason"].map(name => h("th", "", name)))]),
+		h("tbody", "", rows)
+	]));
+}
+
+// findSymbols lists the functions matching the query with the inlining
+// decision about them, their call sites and the calls in them.
+function findSymbols(){
+	var el = document.getElementById("symbols");
+	var query = document.getElementById("symbol-query").value.trim();
+	if(query == "") return;
+	el.innerText = "Searching...";
+	var link = (text, key, line) => {
+		if(!key) return text;
+		var a = h("a", "", text);
+		a.href = "#";
+		a.onclick = () => { openFile(key, line); return false; };
+		return a;
+	};
+	fetch("/api/v1/symbols?q=" + encodeURIComponent(query))
+		.then(response => response.json())
+		.then(symbols => {
+			el.innerText = symbols.length == 0 ? "No functions found." : "";
+			el.appendChild(h("ul", "", symbols.map(symbol => {
+				var inlined = sites => sites.filter(call => call.inlined).length + " of " + sites.length;
+				var site = (call, text) => h("li", call.inlined ? "inlined" : "not-inlined", [
+					link(call.path + ":" + call.line, call.key, call.line),
+					" " + text + (call.inlined ? ": inlined" : ": not inlined")
+				]);
+				return h("li", symbol.status.replace(" ", "-"), [
+					link(symbol.func, symbol.key, symbol.line),
+					" " + symbol.path + ":" + symbol.line + ", " + symbol.status +
+						(symbol.reason ? " (" + symbol.reason + ")" : "") + ", " + symbol.notes + " annotations",
+					h("div", "", "Inlined at " + inlined(symbol.calls) + " call sites:"),
+					h("ul", "", symbol.calls.map(call => site(call, "in " + call.caller))),
+					h("div", "", "Inlined " + inlined(symbol.callees) + " calls in it:"),
+					h("ul", "", symbol.callees.map(call => site(call, call.callee)))
+				]);
+			})));
+		});
+}
+
+// renderOrphans lists notes for lines outside of the file,
+// which happens when the log is older than the source.
+function renderOrphans(fragment, file, columns) {
+	if(!file.orphans || file.orphans.length == 0) return;
+	var header = h("th", "", file.truncated ?
+		"Lines outside of the shown source (" + file.orphans.length + " annotations)" :
+		"Lines outside of the file (" + file.orphans.length + " annotations, the log may be stale)");
+	header.colSpan = columns;
+	fragment.appendChild(h("tr", "orphans-header", [header]));
+
+	var list = h("ul", "", file.orphans.map(note => h("li", "severity-" + note.severity, [
+		"line " + note.line + ": ", h("span", "badge", note.tool), " " + note.severity + ": " + noteText(note)
+	])));
+	var cell = h("td", "", [list]);
+	cell.colSpan = columns;
+	fragment.appendChild(h("tr", "notes orphans", [cell]));
+}
+
+// appendSource appends source[from:to] to el, highlighting the expressions
+// that the notes with spans are about.
+function appendSource(el, source, from, to, spans) {
+	while(from < to){
+		var span = spans.find(note => note.span.start <= from && from < note.span.end);
+		var end = to;
+		if(span){
+			end = Math.min(to, span.span.end);
+		} else {
+			spans.forEach(note => {
+				if(note.span.start > from && note.span.start < end) end = note.span.start;
+			});
+		}
+		var text = document.createTextNode(source.substring(from, end));
+		if(span && span.search !== undefined){
+			var mark = h("mark", "search" + (span.search == search.current ? " current" : ""), [text]);
+			mark.id = "M" + span.search;
+			el.appendChild(mark);
+		} else if(span){
+			var mark = h("mark", "span severity-" + span.severity, [text]);
+			mark.title = noteTitle(span);
+			el.appendChild(mark);
+		} else {
+			el.appendChild(text);
+		}
+		from = end;
+	}
+}
+
+// explanations of compiler messages, the first one matching is shown
+var explanations = {{ .Explanations }} || [];
+
+// explainButton returns a button showing the explanation of note below it,
+// or an empty text when there is none.
+function explainButton(note){
+	var explanation = explanations.find(e => note.message.indexOf(e.match) >= 0);
+	if(!explanation) return "";
+	var panel = h("div", "explain", [
+		h("strong", "", explanation.title),
+		h("p", "", explanation.explanation)
+	]);
+	if(explanation.fix) panel.appendChild(h("p", "", [h("em", "", "Fix: "), explanation.fix]));
+	if(explanation.link){
+		var link = h("a", "", "Learn more");
+		link.href = explanation.link;
+		link.target = "_blank";
+		link.rel = "noopener";
+		panel.appendChild(link);
+	}
+	panel.hidden = true;
+
+	var button = h("button", "explain-button", "Explain");
+	button.type = "button";
+	button.setAttribute("aria-expanded", "false");
+	button.onclick = () => {
+		panel.hidden = !panel.hidden;
+		button.setAttribute("aria-expanded", panel.hidden ? "false" : "true");
+	};
+	return h("span", "", [" ", button, panel]);
+}
+
+function toggleNotes(ev){
+	var button = ev.currentTarget;
+	var notes = document.getElementById(button.getAttribute("aria-controls"));
+	notes.hidden = !notes.hidden;
+	button.setAttribute("aria-expanded", notes.hidden ? "false" : "true");
+	if(lineHistory && !notes.hidden && !notes.dataset.history){
+		notes.dataset.history = "loading";
+		loadLineHistory(notes);
+	}
+}
+
+// lineHistory is set when runs are recorded with -history.
+var lineHistory = {{ .History }};
+
+// loadLineHistory adds to the notes of a line since when each annotation
+// is present and in which of the recorded runs.
+function loadLineHistory(notes){
+	var url = "/api/v1/history?path=" + encodeURIComponent(notes.dataset.path) + "&line=" + notes.dataset.line;
+	fetch(url)
+		.then(response => response.ok ? response.json() : null)
+		.then(history => {
+			if(!history || history.runs.length == 0) return;
+			var runName = run => run.commit ? run.commit.substr(0, 7) : new Date(run.time).toLocaleString();
+			var list = h("ul", "history", history.notes.map(note => {
+				var count = note.present.filter(present => present).length;
+				var strip = h("span", "runs", note.present.map((present, i) => {
+					var el = h("span", present ? "present" : "absent", present ? "\u25cf" : "\u25cb");
+					el.title = runName(history.runs[i]) + ": " + (present ? "present" : "absent");
+					return el;
+				}));
+				var since = note.since < 0 ? "not in the last run" :
+					note.since == 0 ? "since the first recorded run" :
+					"since " + runName(history.runs[note.since]) + " (" + new Date(history.runs[note.since].time).toLocaleDateString() + ")";
+				return h("li", "", [
+					h("span", "badge", note.tool), " " + note.message + ": " + since + ", in " + count + " of " + history.runs.length + " runs ",
+					strip
+				]);
+			}));
+			notes.lastChild.appendChild(h("div", "history-title", "History"));
+			notes.lastChild.appendChild(list);
+		});
+}
+
+function noteTitle(note){
+	return "[" + note.tool + "] " + noteText(note);
+}
+
+function noteText(note){
+	if(note.count > 1){
+		return note.message + " (x" + note.count + ")";
+	}
+	return note.message;
+}
+
+function h(tag, className, children){
+	var el = document.createElement(tag);
+	if(className) el.className = className;
+
+	if((typeof children == "string") || (typeof children == "number")){
+		children = [children];
+	} else if (typeof children == "undefined") {
+		children = [];
+	}
+
+	for(var i = 0; i < children.length; i++){
+		var child = children[i];
+		if(typeof child === "string" || typeof child == "number"){
+			el.appendChild(document.createTextNode(child));
+		} else {
+			el.appendChild(child);
+		}
+	}
+	return el;
+}
+
+loadOrder();
+loadState();
+fileSelected();
@@ -0,0 +1,76 @@
+package main
+
+import "bytes"
+
+// Autogenerated is the path the compiler uses for code it generates itself,
+// e.g. wrapper methods and init functions. Its notes are collected into a
+// synthetic file with a line for each symbol.
+const Autogenerated = "<autogenerated>"
+
+// autogeneratedPrefixes precede the symbol name in compiler messages.
+var autogeneratedPrefixes = [...]string{
+	"inlining call to ",
+	"can inline ",
+	"cannot inline ",
+	"devirtualizing ",
+	"leaking closure reference ",
+	" in ",
+	" for ",
+}
+
+// AddAutogenerated adds a note for <autogenerated> to the synthetic file,
+// at the line of the symbol the message is about.
+func (index *Index) AddAutogenerated(tool string, msg []byte) {
+	file, ok := index.Files[Autogenerated]
+	if !ok {
+		file = &File{
+			Key:     Autogenerated,
+			Path:    Autogenerated,
+			AbsPath: Autogenerated,
+			Source:  []byte{},
+			seen:    make(map[noteKey]int),
+		}
+		index.Files[Autogenerated] = file
+		index.autogenerated = make(map[string]int)
+	}
+
+	// indented lines explain the previous message
+	symbol := index.autogeneratedLast
+	if len(msg) == 0 || (msg[0] != ' ' && msg[0] != '\t') || symbol == "" {
+		symbol = autogeneratedSymbol(msg)
+		index.autogeneratedLast = symbol
+	}
+	line, ok := index.autogenerated[symbol]
+	if !ok {
+		line = len(index.autogenerated)
+		index.autogenerated[symbol] = line
+		if line > 0 {
+			file.Source = append(file.Source, '\n')
+		}
+		file.Source = append(file.Source, symbol...)
+	}
+
+	if file.AddNote(tool, line, -1, msg) {
+		file.Stats.Add(msg)
+	}
+}
+
+// autogeneratedSymbol finds the symbol name in a message about
+// autogenerated code, e.g. "inlining call to (*T).Close" or
+// "(*T).Reset ignoring self-assignment in b.buf = b.buf[:0]".
+func autogeneratedSymbol(msg []byte) string {
+	if fields := bytes.Fields(msg); len(fields) > 0 && bytes.Contains(fields[0], []byte(").")) {
+		return string(fields[0])
+	}
+	for _, prefix := range autogeneratedPrefixes {
+		p := bytes.Index(msg, []byte(prefix))
+		if p < 0 {
+			continue
+		}
+		fields := bytes.Fields(msg[p+len(prefix):])
+		if len(fields) > 0 {
+			return string(bytes.TrimSuffix(fields[0], []byte(":")))
+		}
+	}
+	return "other"
+}
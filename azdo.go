@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// WriteAzureDevOps writes the errors and warnings as logging commands of
+// Azure Pipelines, which list them in the build summary and annotate the
+// lines in pull requests. Azure Pipelines has no issues of lower severity,
+// so the other notes are left out.
+func (index *Index) WriteAzureDevOps(w io.Writer) error {
+	for _, file := range index.SortedFiles() {
+		for _, note := range file.Notes {
+			issue := "warning"
+			switch note.Severity {
+			case Info:
+				continue
+			case Error:
+				issue = "error"
+			}
+
+			code := note.Tool
+			if category := NoteCategory(&note); category != "" {
+				code += "/" + category
+			}
+			column := ""
+			if note.Column >= 0 {
+				column = fmt.Sprintf(";columnnumber=%d", note.Column+1)
+			}
+			_, err := fmt.Fprintf(w, "##vso[task.logissue type=%s;sourcepath=%s;linenumber=%d%s;code=%s]%s\n",
+				issue, azdoPropertyEscaper.Replace(filepath.ToSlash(file.Path)), note.Line+1, column,
+				azdoPropertyEscaper.Replace(code), azdoMessageEscaper.Replace(string(note.Message)))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var azdoMessageEscaper = strings.NewReplacer(
+	"%", "%AZP25",
+	"\r", "%0D",
+	"\n", "%0A",
+)
+
+var azdoPropertyEscaper = strings.NewReplacer(
+	"%", "%AZP25",
+	"\r", "%0D",
+	"\n", "%0A",
+	";", "%3B",
+	"]", "%5D",
+)
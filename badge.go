@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"strconv"
+)
+
+// HeapEscapes returns the number of distinct escapes to heap in the index.
+func (index *Index) HeapEscapes() int {
+	n := 0
+	for _, file := range index.Files {
+		n += file.Stats[1][1]
+	}
+	return n
+}
+
+// Shield is the JSON format of shields.io endpoint badges.
+type Shield struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+const badgeLabel = "heap escapes"
+
+// HeapEscapesShield returns the shield showing the number of heap escapes.
+func (index *Index) HeapEscapesShield() *Shield {
+	return &Shield{
+		SchemaVersion: 1,
+		Label:         badgeLabel,
+		Message:       strconv.Itoa(index.HeapEscapes()),
+		Color:         "blue",
+	}
+}
+
+// Badge parses the logs given in args and writes an SVG badge with the
+// number of heap escapes, e.g. for a README.
+func Badge(args []string, w io.Writer) error {
+	set := flag.NewFlagSet("badge", flag.ExitOnError)
+	label := set.String("label", badgeLabel, "text on the left side of the badge")
+	set.Parse(args)
+
+	inputs := set.Args()
+	if len(inputs) == 0 {
+		inputs = []string{""}
+	}
+	index := NewIndex()
+	dir, _ := filepath.Abs(".")
+	for _, input := range inputs {
+		tool, name := SplitInput(input)
+		data, err := ReadInput(name)
+		if err != nil {
+			return err
+		}
+		index.Parse(dir, tool, data)
+	}
+
+	return WriteBadge(w, *label, strconv.Itoa(index.HeapEscapes()))
+}
+
+// WriteBadge writes a flat SVG badge in the style of shields.io.
+func WriteBadge(w io.Writer, label, message string) error {
+	// approximate text widths of 11px Verdana
+	labelWidth := 10 + 7*len(label)
+	messageWidth := 10 + 7*len(message)
+	width := labelWidth + messageWidth
+
+	label, message = html.EscapeString(label), html.EscapeString(message)
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+<title>%[4]s: %[5]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)">
+<rect width="%[2]d" height="20" fill="#555"/>
+<rect x="%[2]d" width="%[3]d" height="20" fill="#007ec6"/>
+<rect width="%[1]d" height="20" fill="url(#s)"/>
+</g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[6]d" y="14">%[4]s</text>
+<text x="%[7]d" y="14">%[5]s</text>
+</g>
+</svg>
+`, width, labelWidth, messageWidth, label, message, labelWidth/2, labelWidth+messageWidth/2)
+	return err
+}
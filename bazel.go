@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// bazelWorkspaceFiles mark the root of a Bazel workspace.
+var bazelWorkspaceFiles = [...]string{"MODULE.bazel", "WORKSPACE.bazel", "WORKSPACE"}
+
+// FindBazelWorkspace returns the Bazel workspace containing dir,
+// or "" when dir is not in one.
+func FindBazelWorkspace(dir string) string {
+	for {
+		for _, name := range bazelWorkspaceFiles {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// BazelPath translates a path printed by a Bazel build, e.g. with rules_go,
+// to the file in the workspace:
+//
+//	/home/me/.cache/bazel/_bazel_me/1f2e/sandbox/linux-sandbox/7/execroot/_main/pkg/foo.go
+//	    -> workspace/pkg/foo.go
+//	external/org_golang_x_sys/unix/syscall.go
+//	    -> workspace/bazel-workspace/external/org_golang_x_sys/unix/syscall.go
+//	bazel-out/k8-fastbuild/bin/pkg/foo.pb.go
+//	    -> workspace/bazel-out/k8-fastbuild/bin/pkg/foo.pb.go
+//
+// The last two go through the convenience symlinks Bazel creates in the
+// workspace. Other paths are returned unchanged.
+func BazelPath(workspace string, path string) string {
+	slashed := filepath.ToSlash(path)
+
+	// execroot/<workspace name>/ is the root of the sandbox
+	if p := strings.LastIndex(slashed, "/execroot/"); p >= 0 {
+		rest := slashed[p+len("/execroot/"):]
+		if slash := strings.IndexByte(rest, '/'); slash >= 0 {
+			slashed = rest[slash+1:]
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(slashed, "external/"):
+		return filepath.Join(workspace, "bazel-"+filepath.Base(workspace), filepath.FromSlash(slashed))
+	case strings.HasPrefix(slashed, "bazel-out/"):
+		return filepath.Join(workspace, filepath.FromSlash(slashed))
+	case slashed != filepath.ToSlash(path):
+		return filepath.Join(workspace, filepath.FromSlash(slashed))
+	}
+	return path
+}
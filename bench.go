@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Benchmark is the average result of a benchmark over all of its runs.
+type Benchmark struct {
+	Package string
+	Name    string
+	Runs    int
+
+	NsPerOp     float64
+	BytesPerOp  float64
+	AllocsPerOp float64
+}
+
+// ParseBenchmarks parses go test -bench output, keyed by package and name.
+func ParseBenchmarks(data []byte) map[string]*Benchmark {
+	benchmarks := make(map[string]*Benchmark)
+	pkg := ""
+	for _, line := range SplitLines(data) {
+		if bytes.HasPrefix(line, []byte("pkg: ")) {
+			pkg = string(bytes.TrimSpace(line[5:]))
+			continue
+		}
+		if !bytes.HasPrefix(line, []byte("Benchmark")) {
+			continue
+		}
+
+		// BenchmarkFoo-8   1000   1234 ns/op   56 B/op   2 allocs/op
+		fields := strings.Fields(string(line))
+		if len(fields) < 4 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+
+		name := fields[0]
+		key := pkg + "." + name
+		bench, ok := benchmarks[key]
+		if !ok {
+			bench = &Benchmark{Package: pkg, Name: name}
+			benchmarks[key] = bench
+		}
+		bench.Runs++
+
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				bench.NsPerOp += (value - bench.NsPerOp) / float64(bench.Runs)
+			case "B/op":
+				bench.BytesPerOp += (value - bench.BytesPerOp) / float64(bench.Runs)
+			case "allocs/op":
+				bench.AllocsPerOp += (value - bench.AllocsPerOp) / float64(bench.Runs)
+			}
+		}
+	}
+	return benchmarks
+}
+
+// BenchCompare implements the bench-compare subcommand: it reports
+// regressed benchmarks together with the inlining and escape decisions
+// that changed in their packages.
+func BenchCompare(args []string, w io.Writer) error {
+	set := flag.NewFlagSet("bench-compare", flag.ExitOnError)
+	oldBench := set.String("old-bench", "", "benchmark output before the change")
+	newBench := set.String("new-bench", "", "benchmark output after the change")
+	oldLog := set.String("old-log", "", "compiler log before the change")
+	newLog := set.String("new-log", "", "compiler log after the change")
+	threshold := set.Float64("threshold", 5, "minimum slowdown in percent to report a benchmark")
+	codeOwnersFile := set.String("codeowners", "", "CODEOWNERS file to group the changes by owner, found in the current directory by default")
+	weightsFile := set.String("weights", "", "weights of packages to sum up the changed heap escapes with, see -weights")
+	set.Parse(args)
+
+	if *oldBench == "" || *newBench == "" || *oldLog == "" || *newLog == "" {
+		return errors.New("bench-compare: -old-bench, -new-bench, -old-log and -new-log must be specified")
+	}
+
+	dir, _ := filepath.Abs(".")
+	load := func(name string) ([]byte, error) {
+		data, err := ReadInput(name)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", name, err)
+		}
+		return data, nil
+	}
+
+	data, err := load(*oldBench)
+	if err != nil {
+		return err
+	}
+	before := ParseBenchmarks(data)
+
+	data, err = load(*newBench)
+	if err != nil {
+		return err
+	}
+	after := ParseBenchmarks(data)
+
+	oldIndex, newIndex := NewIndex(), NewIndex()
+	data, err = load(*oldLog)
+	if err != nil {
+		return err
+	}
+	oldIndex.Parse(dir, "", data)
+
+	data, err = load(*newLog)
+	if err != nil {
+		return err
+	}
+	newIndex.Parse(dir, "", data)
+
+	changes := DiffIndexes(oldIndex, newIndex)
+	root := newIndex.CanonicalPath("", dir)
+
+	var owners *CodeOwners
+	if path := *codeOwnersFile; path != "" || FindCodeOwners(dir) != "" {
+		if path == "" {
+			path = FindCodeOwners(dir)
+		}
+		if owners, err = LoadCodeOwners(path); err != nil {
+			return err
+		}
+	}
+
+	var weights *Weights
+	if *weightsFile != "" {
+		data, err := load(*weightsFile)
+		if err == nil {
+			weights, err = ParseWeights(root, data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	type regression struct {
+		old, new *Benchmark
+		delta    float64
+	}
+	var regressions []regression
+	for key, old := range before {
+		new, ok := after[key]
+		if !ok || old.NsPerOp == 0 {
+			continue
+		}
+		delta := (new.NsPerOp - old.NsPerOp) / old.NsPerOp * 100
+		if delta >= *threshold {
+			regressions = append(regressions, regression{old, new, delta})
+		}
+	}
+	sort.Slice(regressions, func(i, k int) bool {
+		return regressions[i].delta > regressions[k].delta
+	})
+
+	if len(regressions) == 0 {
+		fmt.Fprintf(w, "No benchmarks regressed by more than %v%%.\n", *threshold)
+		return nil
+	}
+
+	for _, r := range regressions {
+		fmt.Fprintf(w, "%s %s: %.0f ns/op -> %.0f ns/op (%+.1f%%), %.0f -> %.0f allocs/op\n",
+			r.new.Package, r.new.Name, r.old.NsPerOp, r.new.NsPerOp, r.delta,
+			r.old.AllocsPerOp, r.new.AllocsPerOp)
+
+		found := false
+		escapes := 0.0
+		for _, change := range changes {
+			if !InPackage(r.new.Package, root, filepath.Dir(change.Key)) {
+				continue
+			}
+			found = true
+			sign := "-"
+			weight := -weights.Weight(change.Key)
+			if change.Added {
+				sign = "+"
+				weight = -weight
+			}
+			if containsAny(change.Message, allocationKeywords) {
+				escapes += weight
+			}
+			fmt.Fprintf(w, "\t%s %s:%d: %s\n", sign, change.Path, change.Line, change.Message)
+		}
+		if !found {
+			fmt.Fprintf(w, "\tno inlining or escape changes in the package\n")
+		} else if weights != nil {
+			fmt.Fprintf(w, "\tweighted heap escapes: %+.1f\n", escapes)
+		}
+	}
+
+	if owners != nil {
+		WriteOwnerChanges(w, owners, changes)
+	}
+	return nil
+}
+
+// WriteOwnerChanges sums up the changed annotations by the owners of their
+// files.
+func WriteOwnerChanges(w io.Writer, owners *CodeOwners, changes []NoteChange) {
+	type counts struct{ added, removed int }
+	byOwner := make(map[string]*counts)
+	for _, change := range changes {
+		names := owners.Owners(change.Key)
+		if len(names) == 0 {
+			names = []string{noOwner}
+		}
+		for _, name := range names {
+			c := byOwner[name]
+			if c == nil {
+				c = &counts{}
+				byOwner[name] = c
+			}
+			if change.Added {
+				c.added++
+			} else {
+				c.removed++
+			}
+		}
+	}
+
+	names := make([]string, 0, len(byOwner))
+	for name := range byOwner {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, "Changes by owner:\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "\t%s: %d added, %d removed\n", name, byOwner[name].added, byOwner[name].removed)
+	}
+}
+
+// InPackage reports whether dir may hold the package with importPath.
+// Only the directory relative to root is known, so the import path is
+// matched by its suffix.
+func InPackage(importPath string, root string, dir string) bool {
+	if importPath == "" {
+		return true
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		return path.Base(importPath) == filepath.Base(root)
+	}
+	return importPath == rel || strings.HasSuffix(importPath, "/"+rel)
+}
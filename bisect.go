@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// bisectKinds are the annotations counted by bisect, with the compiler
+// flags reporting them and the messages counted.
+var bisectKinds = map[string]struct {
+	gcflags  string
+	keywords []string
+}{
+	"escapes":  {"-m", []string{"escapes to heap", "moved to heap:"}},
+	"inlining": {"-m", statSpecs[0].Bad},
+	"bce":      {"-d=ssa/check_bce/debug=1", statSpecs[2].Bad},
+}
+
+// bisectSkip is the error of a bisect test for a commit that cannot be
+// tested, e.g. because it doesn't build.
+type bisectSkip struct{ error }
+
+// BisectExitCode returns the exit code of the bisect subcommand for err,
+// 125 tells git bisect run to skip the commit.
+func BisectExitCode(err error) int {
+	var skip bisectSkip
+	if errors.As(err, &skip) {
+		return 125
+	}
+	return 1
+}
+
+// Bisect implements the bisect subcommand: it runs git bisect between a
+// good and a bad commit, testing each commit by building the packages and
+// counting the heap escapes, functions that cannot be inlined or bounds
+// checks in a function, to find the commit that introduced a new one.
+// With -run it is the test run by git bisect for the current checkout.
+func Bisect(args []string, w io.Writer) error {
+	set := flag.NewFlagSet("bisect", flag.ExitOnError)
+	good := set.String("good", "", "commit without the regression")
+	bad := set.String("bad", "HEAD", "commit with the regression")
+	fn := set.String("func", "", "function to watch, e.g. Parse or (*Index).Parse")
+	kind := set.String("kind", "escapes", "annotations counted: \"escapes\" (heap escapes), \"inlining\" (cannot inline) or \"bce\" (bounds checks)")
+	max := set.Int("max", -1, "commits with more annotations are bad, the number at the good commit by default")
+	run := set.Bool("run", false, "test the current checkout, used by git bisect run")
+	set.Parse(args)
+
+	if *fn == "" {
+		return errors.New("bisect: -func is required")
+	}
+	if _, ok := bisectKinds[*kind]; !ok {
+		return fmt.Errorf("bisect: unknown kind %q, expected escapes, inlining or bce", *kind)
+	}
+	packages := set.Args()
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+
+	if *run {
+		count, err := countInFunc(*fn, *kind, packages)
+		if err != nil {
+			return bisectSkip{err}
+		}
+		fmt.Fprintf(w, "%s: %d %s, at most %d\n", *fn, count, *kind, *max)
+		if count > *max {
+			return fmt.Errorf("%s has more %s than %d", *fn, *kind, *max)
+		}
+		return nil
+	}
+
+	if *good == "" {
+		return errors.New("bisect: -good is required")
+	}
+	dir, _ := filepath.Abs(".")
+	if status, err := gitOutput(dir, "status", "--porcelain", "--untracked-files=no"); err != nil || status != "" {
+		return errors.New("bisect: the checkout must be a git repository without uncommitted changes")
+	}
+	goodCommit, err := gitOutput(dir, "rev-parse", "--verify", *good+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("bisect: unknown commit %q", *good)
+	}
+	badCommit, err := gitOutput(dir, "rev-parse", "--verify", *bad+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("bisect: unknown commit %q", *bad)
+	}
+
+	// git bisect reset returns to the commit checked out at the start
+	if _, err := gitOutput(dir, "bisect", "start"); err != nil {
+		return fmt.Errorf("git bisect start: %v", err)
+	}
+	defer gitOutput(dir, "bisect", "reset")
+
+	if *max < 0 {
+		if _, err := gitOutput(dir, "checkout", "-q", goodCommit); err != nil {
+			return fmt.Errorf("git checkout %v: %v", *good, err)
+		}
+		if *max, err = countInFunc(*fn, *kind, packages); err != nil {
+			return fmt.Errorf("%v: %v", *good, err)
+		}
+	}
+	if _, err := gitOutput(dir, "checkout", "-q", badCommit); err != nil {
+		return fmt.Errorf("git checkout %v: %v", *bad, err)
+	}
+	count, err := countInFunc(*fn, *kind, packages)
+	if err != nil {
+		return fmt.Errorf("%v: %v", *bad, err)
+	}
+	if count <= *max {
+		return fmt.Errorf("%s has %d %s at %v, not more than %d", *fn, count, *kind, *bad, *max)
+	}
+	fmt.Fprintf(w, "%s has %d %s at %v and %d at %v, bisecting\n", *fn, count, *kind, *bad, *max, *good)
+
+	if _, err := gitOutput(dir, "bisect", "bad", badCommit); err != nil {
+		return fmt.Errorf("git bisect bad: %v", err)
+	}
+	if _, err := gitOutput(dir, "bisect", "good", goodCommit); err != nil {
+		return fmt.Errorf("git bisect good: %v", err)
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("git", append([]string{"bisect", "run", self, "bisect", "-run",
+		"-func", *fn, "-kind", *kind, "-max", strconv.Itoa(*max)}, packages...)...)
+	cmd.Dir = dir
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git bisect run: %v", err)
+	}
+
+	first, err := gitOutput(dir, "log", "-1", "--format=%h %s", "refs/bisect/bad")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nFirst commit with more %s in %s: %s\n", *kind, *fn, first)
+	return nil
+}
+
+// countInFunc builds the packages and returns the number of annotations of
+// kind in the function named fn.
+func countInFunc(fn string, kind string, packages []string) (int, error) {
+	spec := bisectKinds[kind]
+	data, err := compilerOutput(packages, spec.gcflags)
+	if err != nil {
+		return 0, err
+	}
+	dir, _ := filepath.Abs(".")
+	index := NewIndex()
+	index.Parse(dir, "", data)
+
+	count := 0
+	for _, file := range index.Files {
+		_, funcs := index.goFuncs(file.AbsPath)
+		for _, f := range funcs {
+			if f.Name != fn && !strings.HasSuffix(f.Name, ")."+fn) {
+				continue
+			}
+			for _, note := range file.Notes {
+				if note.Line+1 < f.From || note.Line+1 > f.To {
+					continue
+				}
+				for _, keyword := range spec.keywords {
+					if strings.Contains(string(note.Message), keyword) {
+						count++
+						break
+					}
+				}
+			}
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// bitbucketSeverity maps severities to the severities of Code Insights
+// annotations.
+var bitbucketSeverity = map[Severity]string{
+	Info:    "LOW",
+	Warning: "MEDIUM",
+	Error:   "HIGH",
+}
+
+// BitbucketReport is a Code Insights report of Bitbucket Cloud with its
+// annotations, see
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-reports/
+type BitbucketReport struct {
+	Title      string          `json:"title"`
+	Details    string          `json:"details"`
+	ReportType string          `json:"report_type"`
+	Reporter   string          `json:"reporter"`
+	Result     string          `json:"result"`
+	Data       []BitbucketData `json:"data"`
+
+	Annotations []BitbucketAnnotation `json:"-"`
+}
+
+// BitbucketData is a number shown with a report.
+type BitbucketData struct {
+	Title string `json:"title"`
+	Type  string `json:"type"`
+	Value int    `json:"value"`
+}
+
+// BitbucketAnnotation is an annotation of a line of a report.
+type BitbucketAnnotation struct {
+	ExternalID     string `json:"external_id"`
+	AnnotationType string `json:"annotation_type"`
+	Summary        string `json:"summary"`
+	Path           string `json:"path"`
+	Line           int    `json:"line"`
+	Severity       string `json:"severity"`
+}
+
+// BitbucketReport returns the notes of the files in the repository at root
+// as a Code Insights report, which fails when there are errors. At most max
+// annotations are included, errors first.
+func (index *Index) BitbucketReport(root string, max int) *BitbucketReport {
+	report := &BitbucketReport{
+		Title:      "Compiler annotations",
+		ReportType: "BUG",
+		Reporter:   "view-annotated-file",
+		Result:     "PASSED",
+	}
+	counts := make(map[Severity]int)
+	for _, file := range index.SortedFiles() {
+		path, err := repoPath(root, file.AbsPath)
+		if err != nil {
+			continue
+		}
+		funcs := index.fileFuncs(file)
+		for i := range file.Notes {
+			note := &file.Notes[i]
+			counts[note.Severity]++
+			annotationType := "CODE_SMELL"
+			if note.Severity == Error {
+				annotationType = "BUG"
+			}
+			report.Annotations = append(report.Annotations, BitbucketAnnotation{
+				ExternalID:     Fingerprint(file.Path, funcs, note),
+				AnnotationType: annotationType,
+				Summary:        string(note.Message),
+				Path:           path,
+				Line:           note.Line + 1,
+				Severity:       bitbucketSeverity[note.Severity],
+			})
+		}
+	}
+	if counts[Error] > 0 {
+		report.Result = "FAILED"
+	}
+	report.Details = fmt.Sprintf("%d errors, %d warnings and %d other annotations", counts[Error], counts[Warning], counts[Info])
+	report.Data = []BitbucketData{
+		{Title: "Errors", Type: "NUMBER", Value: counts[Error]},
+		{Title: "Warnings", Type: "NUMBER", Value: counts[Warning]},
+		{Title: "Info", Type: "NUMBER", Value: counts[Info]},
+	}
+
+	annotations := report.Annotations
+	if len(annotations) > max {
+		// the most severe ones are kept, in file order
+		byRank := make([]BitbucketAnnotation, 0, len(annotations))
+		for _, severity := range []string{"HIGH", "MEDIUM", "LOW"} {
+			for _, annotation := range annotations {
+				if annotation.Severity == severity {
+					byRank = append(byRank, annotation)
+				}
+			}
+		}
+		report.Annotations = byRank[:max]
+	}
+	return report
+}
+
+// PublishBitbucket implements the publish-bitbucket subcommand: it creates a
+// Code Insights report of a commit with the annotations of the logs, which
+// Bitbucket shows on the diffs of pull requests. With -server the report is
+// posted to Bitbucket Server or Data Center, Bitbucket Cloud otherwise.
+func PublishBitbucket(args []string, w io.Writer) error {
+	set := flag.NewFlagSet("publish-bitbucket", flag.ExitOnError)
+	repo := set.String("repo", os.Getenv("BITBUCKET_REPO_FULL_NAME"), "repository as workspace/slug, or PROJECT/slug with -server, defaults to $BITBUCKET_REPO_FULL_NAME")
+	commit := set.String("commit", os.Getenv("BITBUCKET_COMMIT"), "commit of the report, defaults to $BITBUCKET_COMMIT or HEAD")
+	server := set.String("server", "", "URL of Bitbucket Server or Data Center")
+	api := set.String("api", "https://api.bitbucket.org/2.0", "API of Bitbucket Cloud, in Pipelines http://api.bitbucket.org/2.0 with the proxy http://localhost:29418")
+	reportID := set.String("report", "view-annotated-file", "id of the report, a new one replaces the last of the commit")
+	max := set.Int("max", 1000, "maximum number of annotations, Bitbucket keeps up to 1000")
+	dryRun := set.Bool("dry-run", false, "print the report and its annotations as JSON instead of posting them")
+	set.Parse(args)
+
+	dir, _ := filepath.Abs(".")
+	if *commit == "" {
+		*commit, _ = gitOutput(dir, "rev-parse", "HEAD")
+	}
+	if *repo == "" || *commit == "" {
+		return errors.New("publish-bitbucket: -repo and -commit must be specified")
+	}
+
+	inputs := set.Args()
+	if len(inputs) == 0 {
+		inputs = []string{""}
+	}
+	index := NewIndex()
+	for _, input := range inputs {
+		tool, name := SplitInput(input)
+		data, err := ReadInput(name)
+		if err != nil {
+			return err
+		}
+		index.Parse(dir, tool, data)
+	}
+	report := index.BitbucketReport(repoRoot(dir), *max)
+
+	if *dryRun {
+		data, err := json.MarshalIndent(struct {
+			*BitbucketReport
+			Annotations []BitbucketAnnotation `json:"annotations"`
+		}{report, report.Annotations}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\n", data)
+		return nil
+	}
+
+	if *server != "" {
+		err := publishBitbucketServer(strings.TrimSuffix(*server, "/"), *repo, *commit, *reportID, report)
+		if err != nil {
+			return err
+		}
+	} else {
+		url := fmt.Sprintf("%s/repositories/%s/commit/%s/reports/%s", strings.TrimSuffix(*api, "/"), *repo, *commit, *reportID)
+		if err := bitbucketRequest(http.MethodPut, url, report); err != nil {
+			return err
+		}
+		// Bitbucket Cloud takes up to 100 annotations per request
+		for start := 0; start < len(report.Annotations); start += 100 {
+			end := start + 100
+			if end > len(report.Annotations) {
+				end = len(report.Annotations)
+			}
+			if err := bitbucketRequest(http.MethodPost, url+"/annotations", report.Annotations[start:end]); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Fprintf(w, "Published report %s of %s with %d annotations: %s.\n", *reportID, *commit, len(report.Annotations), report.Details)
+	return nil
+}
+
+// publishBitbucketServer posts the report with the Code Insights API of
+// Bitbucket Server, which names the fields differently.
+func publishBitbucketServer(server, repo, commit, reportID string, report *BitbucketReport) error {
+	project, slug, ok := strings.Cut(repo, "/")
+	if !ok {
+		return fmt.Errorf("publish-bitbucket: -repo %q is not PROJECT/slug", repo)
+	}
+	result := "PASS"
+	if report.Result == "FAILED" {
+		result = "FAIL"
+	}
+	url := fmt.Sprintf("%s/rest/insights/1.0/projects/%s/repos/%s/commits/%s/reports/%s", server, project, slug, commit, reportID)
+	err := bitbucketRequest(http.MethodPut, url, map[string]interface{}{
+		"title":    report.Title,
+		"details":  report.Details,
+		"reporter": report.Reporter,
+		"result":   result,
+		"data":     report.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	// annotations of the report are replaced
+	if err := bitbucketRequest(http.MethodDelete, url+"/annotations", nil); err != nil {
+		return err
+	}
+	annotations := make([]map[string]interface{}, len(report.Annotations))
+	for i, annotation := range report.Annotations {
+		annotations[i] = map[string]interface{}{
+			"externalId": annotation.ExternalID,
+			"type":       annotation.AnnotationType,
+			"message":    annotation.Summary,
+			"path":       annotation.Path,
+			"line":       annotation.Line,
+			"severity":   annotation.Severity,
+		}
+	}
+	return bitbucketRequest(http.MethodPost, url+"/annotations", map[string]interface{}{
+		"annotations": annotations,
+	})
+}
+
+// bitbucketRequest sends body as JSON, authenticated with $BITBUCKET_TOKEN
+// or $BITBUCKET_USER and $BITBUCKET_APP_PASSWORD when they are set.
+func bitbucketRequest(method, url string, body interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := os.Getenv("BITBUCKET_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if user := os.Getenv("BITBUCKET_USER"); user != "" {
+		req.SetBasicAuth(user, os.Getenv("BITBUCKET_APP_PASSWORD"))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%v %v: %v: %s", method, url, resp.Status, bytes.TrimSpace(data))
+	}
+	return nil
+}
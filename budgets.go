@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Budget is the maximum number of heap escapes and functions that cannot
+// be inlined in a package, -1 is no limit.
+type Budget struct {
+	Escapes      int
+	CannotInline int
+}
+
+// Budgets are the budgets of packages, for hardening a large codebase one
+// package at a time. A budgets file has a package import path and its
+// limits on each line, "*" sets the budget of every package not listed:
+//
+//	example.com/app/codec escapes=10 cannot-inline=3
+//	example.com/app/server escapes=40
+//	* escapes=100
+type Budgets struct {
+	Packages map[string]Budget
+	Default  Budget
+
+	// root is the directory package paths are matched against, see InPackage.
+	root string
+}
+
+// ParseBudgets parses a budgets file for packages in root.
+func ParseBudgets(root string, data []byte) (*Budgets, error) {
+	budgets := &Budgets{
+		Packages: make(map[string]Budget),
+		Default:  Budget{-1, -1},
+		root:     root,
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected a package and its budget", n)
+		}
+		budget := Budget{-1, -1}
+		for _, field := range fields[1:] {
+			eq := strings.IndexByte(field, '=')
+			if eq < 0 {
+				return nil, fmt.Errorf("line %d: expected escapes=N or cannot-inline=N, got %q", n, field)
+			}
+			limit, err := strconv.Atoi(field[eq+1:])
+			if err != nil || limit < 0 {
+				return nil, fmt.Errorf("line %d: invalid limit %q", n, field)
+			}
+			switch field[:eq] {
+			case "escapes":
+				budget.Escapes = limit
+			case "cannot-inline":
+				budget.CannotInline = limit
+			default:
+				return nil, fmt.Errorf("line %d: unknown limit %q, expected escapes or cannot-inline", n, field[:eq])
+			}
+		}
+		if fields[0] == "*" {
+			budgets.Default = budget
+		} else {
+			budgets.Packages[fields[0]] = budget
+		}
+	}
+	return budgets, scanner.Err()
+}
+
+// PackageBudget is the use of the budget of a package.
+type PackageBudget struct {
+	Package string
+	Budget  Budget
+	Used    Budget
+}
+
+// Over reports whether the package exceeds its budget.
+func (pkg *PackageBudget) Over() bool {
+	return overLimit(pkg.Used.Escapes, pkg.Budget.Escapes) || overLimit(pkg.Used.CannotInline, pkg.Budget.CannotInline)
+}
+
+func overLimit(used, limit int) bool { return limit >= 0 && used > limit }
+
+// Check returns the budgets of the packages in the index, those listed in
+// the budgets and, when there is a default budget, all other packages,
+// sorted by package.
+func (budgets *Budgets) Check(index *Index) []PackageBudget {
+	byPackage := make(map[string]*PackageBudget)
+	for pkg, budget := range budgets.Packages {
+		byPackage[pkg] = &PackageBudget{Package: pkg, Budget: budget}
+	}
+	for _, file := range index.Files {
+		pkg, budget := budgets.find(filepath.Dir(file.AbsPath))
+		if pkg == "" {
+			pkg = filepath.ToSlash(filepath.Dir(file.Path))
+		}
+		used, ok := byPackage[pkg]
+		if !ok {
+			used = &PackageBudget{Package: pkg, Budget: budget}
+			byPackage[pkg] = used
+		}
+		used.Used.Escapes += file.Stats[1][1]
+		used.Used.CannotInline += file.Stats[0][1]
+	}
+
+	result := []PackageBudget{}
+	for _, pkg := range byPackage {
+		if pkg.Budget != (Budget{-1, -1}) {
+			result = append(result, *pkg)
+		}
+	}
+	sort.Slice(result, func(i, k int) bool {
+		return result[i].Package < result[k].Package
+	})
+	return result
+}
+
+// find returns the longest package listed matching dir and its budget, or
+// "" and the default budget.
+func (budgets *Budgets) find(dir string) (string, Budget) {
+	matched, budget := "", budgets.Default
+	for pkg, b := range budgets.Packages {
+		if len(pkg) > len(matched) && InPackage(pkg, budgets.root, dir) {
+			matched, budget = pkg, b
+		}
+	}
+	return matched, budget
+}
+
+// Check parses the logs given in args and reports the packages exceeding
+// the budgets of a budgets file and the annotations of at least
+// -min-severity, returns an error when there are any.
+func Check(args []string, w io.Writer) error {
+	set := flag.NewFlagSet("check", flag.ExitOnError)
+	budgetsFile := set.String("budgets", "", "file with the budgets of packages, \"import/path escapes=N cannot-inline=N\" per line")
+	minSeverity := set.String("min-severity", "", "fail on annotations of this severity or higher: \"info\", \"warning\" or \"error\"")
+	verbose := set.Bool("v", false, "also list the packages within their budget")
+	set.Parse(args)
+
+	if *budgetsFile == "" && *minSeverity == "" {
+		return errors.New("check: -budgets or -min-severity is required")
+	}
+	threshold, ok := ParseSeverity(*minSeverity)
+	if *minSeverity != "" && !ok {
+		return fmt.Errorf("check: unknown severity %q", *minSeverity)
+	}
+	dir, _ := filepath.Abs(".")
+	budgets, _ := ParseBudgets(dir, nil)
+	if *budgetsFile != "" {
+		data, err := ReadInput(*budgetsFile)
+		if err != nil {
+			return err
+		}
+		budgets, err = ParseBudgets(dir, data)
+		if err != nil {
+			return fmt.Errorf("%v: %v", *budgetsFile, err)
+		}
+	}
+
+	inputs := set.Args()
+	if len(inputs) == 0 {
+		inputs = []string{""}
+	}
+	index := NewIndex()
+	for _, input := range inputs {
+		tool, name := SplitInput(input)
+		data, err := ReadInput(name)
+		if err != nil {
+			return err
+		}
+		index.Parse(dir, tool, data)
+	}
+
+	severe := 0
+	if *minSeverity != "" {
+		for _, file := range index.SortedFiles() {
+			for _, note := range file.Notes {
+				if note.Severity >= threshold {
+					severe++
+					fmt.Fprintf(w, "%s:%d: %v: %s (%s)\n", file.Path, note.Line+1, note.Severity, note.Message, note.Tool)
+				}
+			}
+		}
+	}
+
+	over := 0
+	for _, pkg := range budgets.Check(index) {
+		if pkg.Over() {
+			over++
+		} else if !*verbose {
+			continue
+		}
+		fmt.Fprintf(w, "%s:%s%s\n", pkg.Package,
+			formatLimit("heap escapes", pkg.Used.Escapes, pkg.Budget.Escapes),
+			formatLimit("cannot inline", pkg.Used.CannotInline, pkg.Budget.CannotInline))
+	}
+	if over > 0 {
+		return fmt.Errorf("%d packages exceed their budget", over)
+	}
+	if severe > 0 {
+		return fmt.Errorf("%d annotations are %v or more severe", severe, threshold)
+	}
+	return nil
+}
+
+// formatLimit formats the use of a limit with its difference to the budget,
+// e.g. " heap escapes 12/10 (+2)".
+func formatLimit(name string, used, limit int) string {
+	if limit < 0 {
+		return ""
+	}
+	return fmt.Sprintf(" %s %d/%d (%+d)", name, used, limit, used-limit)
+}
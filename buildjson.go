@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+func init() { RegisterParser(50, buildJSONParser{}) }
+
+// buildJSONParser handles the JSON output of go build -json and
+// go test -json, where diagnostics are embedded in output events:
+//
+//	{"ImportPath":"example.com/pkg","Action":"build-output","Output":"./foo.go:5:6: can inline f\n"}
+type buildJSONParser struct{}
+
+// BuildEvent is an event of go build -json or go test -json.
+type BuildEvent struct {
+	ImportPath string
+	Action     string
+	Output     string
+}
+
+func (buildJSONParser) Detect(line []byte) bool { return IsBuildEvent(line) }
+
+func (buildJSONParser) Parse(index *Index, dir string, lines [][]byte, at int) int {
+	// the output of consecutive events is parsed together,
+	// so that multi-line reports split across events are recognized
+	var output []byte
+	i := at
+	for ; i < len(lines) && IsBuildEvent(lines[i]); i++ {
+		var event BuildEvent
+		if err := json.Unmarshal(lines[i], &event); err != nil {
+			break
+		}
+		switch event.Action {
+		case "build-output", "output":
+			output = append(output, event.Output...)
+		}
+	}
+	if i == at {
+		return at
+	}
+
+	index.ParseLines(dir, "compiler", SplitLines(output))
+	return i - 1
+}
+
+// IsBuildEvent reports whether line is a JSON event of go build -json.
+func IsBuildEvent(line []byte) bool {
+	return bytes.HasPrefix(line, []byte(`{"`)) && bytes.Contains(line, []byte(`"Action":`))
+}
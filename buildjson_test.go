@@ -0,0 +1,11 @@
+package main
+
+import "testing"
+
+func TestBuildJSONParser(t *testing.T) {
+	checkParser(t, buildJSONParser{}, `
+{"ImportPath":"example.com/pkg","Action":"build-output","Output":"# example.com/pkg\n"}
+{"ImportPath":"example.com/pkg","Action":"build-output","Output":"./foo.go:5:6: can inline f\n"}`, []string{
+		"foo.go:5:6: compiler: can inline f",
+	})
+}
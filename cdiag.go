@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+)
+
+func init() { RegisterParser(40, cParser{}) }
+
+type cParser struct{}
+
+func (cParser) Detect(line []byte) bool { return IsCDiagnostic(line) }
+
+func (cParser) Parse(index *Index, dir string, lines [][]byte, at int) int {
+	return index.AddCDiagnostic(dir, lines, at)
+}
+
+var cExtensions = map[string]bool{
+	".c": true, ".h": true, ".cc": true, ".cpp": true, ".cxx": true,
+	".hh": true, ".hpp": true, ".hxx": true, ".m": true, ".mm": true,
+}
+
+var cSeverities = [...]string{"warning: ", "error: ", "fatal error: ", "note: "}
+
+// IsCDiagnostic reports whether line is a gcc or clang diagnostic, e.g. from cgo:
+//
+//	foo.c:12:5: warning: unused variable 'x' [-Wunused-variable]
+func IsCDiagnostic(line []byte) bool {
+	path, _, _, msg, ok := ParseFileLine(line)
+	if !ok || !cExtensions[filepath.Ext(string(path))] {
+		return false
+	}
+	for _, severity := range cSeverities {
+		if bytes.HasPrefix(msg, []byte(severity)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddCDiagnostic adds the diagnostic at lines[at] and skips the source
+// context and caret lines following it, returns the index of the last line
+// belonging to the diagnostic.
+func (index *Index) AddCDiagnostic(dir string, lines [][]byte, at int) int {
+	index.Add(dir, "cc", lines[at])
+
+	i := at + 1
+	for i < len(lines) {
+		switch {
+		case isGCCContext(lines[i]):
+			i++
+		case i+1 < len(lines) && isCaretLine(lines[i+1]):
+			i += 2
+		default:
+			return i - 1
+		}
+	}
+	return i - 1
+}
+
+// isGCCContext reports whether line is source context printed by gcc 9+:
+//
+//	12 |     int x;
+//	   |         ^
+func isGCCContext(line []byte) bool {
+	trimmed := bytes.TrimLeft(line, " 0123456789")
+	return len(trimmed) < len(line) && bytes.HasPrefix(trimmed, []byte("|"))
+}
+
+// isCaretLine reports whether line only marks a position in the line above it,
+// as printed by clang and older gcc:
+//
+//	^~~~
+func isCaretLine(line []byte) bool {
+	return bytes.IndexByte(line, '^') >= 0 && len(bytes.Trim(line, " \t^~")) == 0
+}
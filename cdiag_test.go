@@ -0,0 +1,12 @@
+package main
+
+import "testing"
+
+func TestCParser(t *testing.T) {
+	checkParser(t, cParser{}, `
+foo.c:12:5: warning: unused variable 'x' [-Wunused-variable]
+   12 |     int x;
+      |         ^`, []string{
+		"foo.c:12:5: cc: warning: unused variable 'x' [-Wunused-variable]",
+	})
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+func init() { RegisterInputParser(150, clangDiagnosticsParser{}) }
+
+type clangDiagnosticsParser struct{}
+
+func (clangDiagnosticsParser) DetectInput(data []byte) bool { return IsClangDiagnostics(data) }
+
+func (clangDiagnosticsParser) ParseInput(index *Index, dir string, data []byte) error {
+	return index.ParseClangDiagnostics(dir, data)
+}
+
+// clangDiagnosticsMagic starts the files clang writes with
+// --serialize-diagnostics, an LLVM bitstream.
+var clangDiagnosticsMagic = []byte("DIAG")
+
+// IsClangDiagnostics reports whether data is a clang serialized diagnostics file.
+func IsClangDiagnostics(data []byte) bool {
+	return bytes.HasPrefix(data, clangDiagnosticsMagic)
+}
+
+// block and record ids of clang serialized diagnostics
+const (
+	clangBlockDiag = 9
+
+	clangRecordDiag     = 2
+	clangRecordDiagFlag = 4
+	clangRecordCategory = 5
+	clangRecordFilename = 6
+)
+
+var clangSeverity = [...]struct {
+	Name     string
+	Severity Severity
+}{
+	{"ignored", Info},
+	{"note", Info},
+	{"warning", Warning},
+	{"error", Error},
+	{"fatal error", Error},
+	{"remark", Info},
+}
+
+// ParseClangDiagnostics adds the diagnostics of a clang serialized
+// diagnostics file to the index. Notes are labeled "cc", like the ones
+// parsed from compiler output, and categorized by the warning flag.
+func (index *Index) ParseClangDiagnostics(dir string, data []byte) error {
+	files := map[uint64]string{}
+	flags := map[uint64]string{}
+	categories := map[uint64]string{}
+
+	stream := &bitstream{data: data, pos: len(clangDiagnosticsMagic) * 8}
+	return stream.read(func(block uint64, record []uint64, blob []byte) {
+		if block != clangBlockDiag || len(record) == 0 {
+			return
+		}
+		switch record[0] {
+		case clangRecordFilename:
+			files[record[1]] = string(blob)
+		case clangRecordDiagFlag:
+			flags[record[1]] = string(blob)
+		case clangRecordCategory:
+			categories[record[1]] = string(blob)
+		case clangRecordDiag:
+			// severity, file, line, column, offset, category, flag, text size
+			if len(record) < 8 || record[2] == 0 || files[record[2]] == "" {
+				return
+			}
+			severity := clangSeverity[0]
+			if record[1] < uint64(len(clangSeverity)) {
+				severity = clangSeverity[record[1]]
+			}
+			category := flags[record[7]]
+			if category == "" {
+				category = categories[record[6]]
+			}
+
+			_, file := index.File(dir, files[record[2]])
+			file.AddStructuredNote(Note{
+				Line:     int(record[3]) - 1,
+				Column:   int(record[4]) - 1,
+				Message:  []byte(severity.Name + ": " + string(blob)),
+				Tool:     "cc",
+				Severity: severity.Severity,
+				Category: category,
+			})
+		}
+	})
+}
+
+var errBitstreamEnd = errors.New("unexpected end of bitstream")
+
+// bitstream reads the LLVM bitstream container format, see
+// https://llvm.org/docs/BitCodeFormat.html
+type bitstream struct {
+	data []byte
+	pos  int // in bits
+	err  error
+
+	// blockInfo are abbreviations defined in the BLOCKINFO block by block id.
+	blockInfo map[uint64][]bitAbbrev
+}
+
+type bitAbbrev []bitAbbrevOp
+
+type bitAbbrevOp struct {
+	Literal  bool
+	Value    uint64 // literal value or the width of fixed and vbr
+	Encoding uint64
+}
+
+// encodings of abbreviation operands
+const (
+	bitFixed = 1
+	bitVBR   = 2
+	bitArray = 3
+	bitChar6 = 4
+	bitBlob  = 5
+)
+
+// bitRecordFunc receives the records of the stream, record[0] is the record code.
+type bitRecordFunc func(block uint64, record []uint64, blob []byte)
+
+func (stream *bitstream) read(fn bitRecordFunc) error {
+	stream.blockInfo = map[uint64][]bitAbbrev{}
+	for stream.err == nil && stream.pos+32 <= len(stream.data)*8 {
+		// only blocks are allowed at the top level
+		if id := stream.fixed(2); id != 1 {
+			return fmt.Errorf("invalid bitstream: abbreviation %d at top level", id)
+		}
+		stream.block(fn)
+	}
+	return stream.err
+}
+
+func (stream *bitstream) fixed(width uint64) uint64 {
+	var v uint64
+	for i := uint64(0); i < width; i++ {
+		if stream.pos >= len(stream.data)*8 {
+			stream.err = errBitstreamEnd
+			return 0
+		}
+		bit := stream.data[stream.pos/8] >> (stream.pos % 8) & 1
+		v |= uint64(bit) << i
+		stream.pos++
+	}
+	return v
+}
+
+func (stream *bitstream) vbr(width uint64) uint64 {
+	if width == 0 {
+		return 0
+	}
+	var v uint64
+	hi := uint64(1) << (width - 1)
+	for shift := uint64(0); shift < 64; shift += width - 1 {
+		piece := stream.fixed(width)
+		v |= (piece &^ hi) << shift
+		if piece&hi == 0 || stream.err != nil {
+			break
+		}
+	}
+	return v
+}
+
+func (stream *bitstream) align32() { stream.pos = (stream.pos + 31) / 32 * 32 }
+
+// block reads a block after its ENTER_SUBBLOCK abbreviation id.
+func (stream *bitstream) block(fn bitRecordFunc) {
+	id := stream.vbr(8)
+	width := stream.vbr(4)
+	stream.align32()
+	stream.fixed(32) // length in words
+
+	abbrevs := append([]bitAbbrev{}, stream.blockInfo[id]...)
+	var infoBlock uint64 // block the BLOCKINFO abbreviations are for
+
+	for stream.err == nil {
+		switch abbrev := stream.fixed(width); abbrev {
+		case 0: // END_BLOCK
+			stream.align32()
+			return
+		case 1: // ENTER_SUBBLOCK
+			stream.block(fn)
+		case 2: // DEFINE_ABBREV
+			defined := stream.defineAbbrev()
+			if id == 0 {
+				stream.blockInfo[infoBlock] = append(stream.blockInfo[infoBlock], defined)
+			} else {
+				abbrevs = append(abbrevs, defined)
+			}
+		case 3: // UNABBREV_RECORD
+			code := stream.vbr(6)
+			record := []uint64{code}
+			for n := stream.vbr(6); n > 0 && stream.err == nil; n-- {
+				record = append(record, stream.vbr(6))
+			}
+			if id == 0 && code == 1 { // SETBID
+				if len(record) > 1 {
+					infoBlock = record[1]
+				}
+				continue
+			}
+			fn(id, record, nil)
+		default:
+			if abbrev-4 >= uint64(len(abbrevs)) {
+				stream.err = fmt.Errorf("invalid bitstream: undefined abbreviation %d", abbrev)
+				return
+			}
+			record, blob := stream.abbreviated(abbrevs[abbrev-4])
+			if stream.err == nil {
+				fn(id, record, blob)
+			}
+		}
+	}
+}
+
+func (stream *bitstream) defineAbbrev() bitAbbrev {
+	var abbrev bitAbbrev
+	for n := stream.vbr(5); n > 0 && stream.err == nil; n-- {
+		if stream.fixed(1) == 1 {
+			abbrev = append(abbrev, bitAbbrevOp{Literal: true, Value: stream.vbr(8)})
+			continue
+		}
+		op := bitAbbrevOp{Encoding: stream.fixed(3)}
+		if op.Encoding == bitFixed || op.Encoding == bitVBR {
+			op.Value = stream.vbr(5)
+		}
+		abbrev = append(abbrev, op)
+	}
+	return abbrev
+}
+
+// abbreviated reads a record defined by abbrev.
+func (stream *bitstream) abbreviated(abbrev bitAbbrev) (record []uint64, blob []byte) {
+	for i := 0; i < len(abbrev) && stream.err == nil; i++ {
+		op := abbrev[i]
+		switch {
+		case op.Literal:
+			record = append(record, op.Value)
+		case op.Encoding == bitArray:
+			if i+1 >= len(abbrev) {
+				stream.err = errors.New("invalid bitstream: array without element type")
+				return
+			}
+			element := abbrev[i+1]
+			for n := stream.vbr(6); n > 0 && stream.err == nil; n-- {
+				record = append(record, stream.scalar(element))
+			}
+			i++
+		case op.Encoding == bitBlob:
+			n := int(stream.vbr(6))
+			stream.align32()
+			start := stream.pos / 8
+			if start+n > len(stream.data) {
+				stream.err = errBitstreamEnd
+				return
+			}
+			blob = stream.data[start : start+n]
+			stream.pos += n * 8
+			stream.align32()
+		default:
+			record = append(record, stream.scalar(op))
+		}
+	}
+	return record, blob
+}
+
+func (stream *bitstream) scalar(op bitAbbrevOp) uint64 {
+	switch op.Encoding {
+	case bitFixed:
+		return stream.fixed(op.Value)
+	case bitVBR:
+		return stream.vbr(op.Value)
+	case bitChar6:
+		return uint64("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789._"[stream.fixed(6)])
+	}
+	if op.Literal {
+		return op.Value
+	}
+	stream.err = fmt.Errorf("invalid bitstream: unknown encoding %d", op.Encoding)
+	return 0
+}
@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+// bitWriter writes the LLVM bitstream container format read by bitstream.
+type bitWriter struct {
+	data []byte
+	pos  int // in bits
+}
+
+func (w *bitWriter) fixed(v, width uint64) {
+	for i := uint64(0); i < width; i++ {
+		if w.pos/8 >= len(w.data) {
+			w.data = append(w.data, 0)
+		}
+		w.data[w.pos/8] |= byte(v>>i&1) << (w.pos % 8)
+		w.pos++
+	}
+}
+
+func (w *bitWriter) vbr(v, width uint64) {
+	hi := uint64(1) << (width - 1)
+	for v >= hi {
+		w.fixed(v&(hi-1)|hi, width)
+		v >>= width - 1
+	}
+	w.fixed(v, width)
+}
+
+func (w *bitWriter) align32() {
+	for w.pos%32 != 0 {
+		w.fixed(0, 1)
+	}
+}
+
+// abbrev defines an abbreviation of n VBR6 operands followed by a blob.
+func (w *bitWriter) abbrev(n int) {
+	w.fixed(2, 4) // DEFINE_ABBREV
+	w.vbr(uint64(n+1), 5)
+	for i := 0; i < n; i++ {
+		w.fixed(0, 1)
+		w.fixed(bitVBR, 3)
+		w.vbr(6, 5)
+	}
+	w.fixed(0, 1)
+	w.fixed(bitBlob, 3)
+}
+
+// record writes record with the abbreviation id, see abbrev.
+func (w *bitWriter) record(id uint64, blob string, record ...uint64) {
+	w.fixed(id, 4)
+	for _, v := range record {
+		w.vbr(v, 6)
+	}
+	w.vbr(uint64(len(blob)), 6)
+	w.align32()
+	for i := 0; i < len(blob); i++ {
+		w.fixed(uint64(blob[i]), 8)
+	}
+	w.align32()
+}
+
+func TestClangDiagnosticsParser(t *testing.T) {
+	w := &bitWriter{data: append([]byte{}, clangDiagnosticsMagic...), pos: len(clangDiagnosticsMagic) * 8}
+	w.fixed(1, 2) // ENTER_SUBBLOCK
+	w.vbr(clangBlockDiag, 8)
+	w.vbr(4, 4)
+	w.align32()
+	w.fixed(0, 32)
+
+	w.abbrev(4) // id 4: filename, id, size and modification time
+	w.abbrev(2) // id 5: flag and id
+	w.abbrev(8) // id 6: diagnostic
+	w.record(4, "main.c", clangRecordFilename, 1, 0, 0)
+	w.record(5, "-Wunused-variable", clangRecordDiagFlag, 1)
+	// severity, file, line, column, offset, category, flag
+	w.record(6, "unused variable 'x'", clangRecordDiag, 2, 1, 3, 5, 0, 0, 1)
+
+	w.fixed(0, 4) // END_BLOCK
+	w.align32()
+
+	checkInputParser(t, clangDiagnosticsParser{}, w.data, []string{
+		"main.c:3:5: cc: warning: unused variable 'x'",
+	})
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CodeOwners maps files to their owners with the rules of a CODEOWNERS file,
+// the last matching rule wins:
+//
+//	*.go           @org/go-team
+//	/internal/db/  @org/storage @alice
+type CodeOwners struct {
+	// Root is the directory the patterns are relative to.
+	Root  string
+	rules []ownerRule
+}
+
+type ownerRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// codeOwnersPaths are where GitHub and GitLab look for CODEOWNERS.
+var codeOwnersPaths = []string{"CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// FindCodeOwners returns the CODEOWNERS file of the repository in root,
+// or an empty string when there is none.
+func FindCodeOwners(root string) string {
+	for _, path := range codeOwnersPaths {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// LoadCodeOwners reads the CODEOWNERS file at path, its patterns are
+// relative to the repository it is in.
+func LoadCodeOwners(path string) (*CodeOwners, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	root, _ := filepath.Abs(filepath.Dir(path))
+	switch filepath.Base(root) {
+	case ".github", ".gitlab", "docs":
+		root = filepath.Dir(root)
+	}
+	return ParseCodeOwners(root, data), nil
+}
+
+// ParseCodeOwners parses the rules of a CODEOWNERS file with paths relative
+// to root. GitLab sections ("[Section]") are ignored.
+func ParseCodeOwners(root string, data []byte) *CodeOwners {
+	owners := &CodeOwners{Root: root}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		pattern, err := regexp.Compile(ownerPattern(fields[0]))
+		if err != nil {
+			continue
+		}
+		owners.rules = append(owners.rules, ownerRule{pattern, fields[1:]})
+	}
+	return owners
+}
+
+// ownerPattern translates a gitignore-style pattern to a regexp matching
+// slash-separated paths relative to the root.
+func ownerPattern(pattern string) string {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dir := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dir {
+		// only the files inside the directory
+		re.WriteString("/.*")
+	} else {
+		// the file, or the files inside the directory
+		re.WriteString("(?:/.*)?")
+	}
+	re.WriteString("$")
+	return re.String()
+}
+
+// Owners returns the owners of the file at the absolute path, nil when no
+// rule matches or the file is outside of the repository.
+func (owners *CodeOwners) Owners(path string) []string {
+	if owners == nil {
+		return nil
+	}
+	rel, err := filepath.Rel(owners.Root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+	for i := len(owners.rules) - 1; i >= 0; i-- {
+		if owners.rules[i].pattern.MatchString(rel) {
+			return owners.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// noOwner groups the files without owners.
+const noOwner = "(no owner)"
+
+// OwnerStats are the stats of the files of an owner.
+type OwnerStats struct {
+	Owner string `json:"owner"`
+	Files int    `json:"files"`
+	Stats Stats  `json:"stats"`
+}
+
+// OwnerStats sums up the stats of the files by their owners, files with
+// several owners count for each of them. Owners are sorted by heap escapes.
+func (index *Index) OwnerStats() []OwnerStats {
+	byOwner := make(map[string]*OwnerStats)
+	for _, file := range index.Files {
+		owners := index.CodeOwners.Owners(file.AbsPath)
+		if len(owners) == 0 {
+			owners = []string{noOwner}
+		}
+		for _, owner := range owners {
+			stats := byOwner[owner]
+			if stats == nil {
+				stats = &OwnerStats{Owner: owner}
+				byOwner[owner] = stats
+			}
+			stats.Files++
+			for i, v := range file.Stats {
+				stats.Stats[i][0] += v[0]
+				stats.Stats[i][1] += v[1]
+			}
+		}
+	}
+
+	result := []OwnerStats{}
+	for _, stats := range byOwner {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, k int) bool {
+		a, b := &result[i], &result[k]
+		if a.Stats[1][1] != b.Stats[1][1] {
+			return a.Stats[1][1] > b.Stats[1][1]
+		}
+		return a.Owner < b.Owner
+	})
+	return result
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func init() { RegisterParser(90, compilerJSONParser{}) }
+
+// compilerJSONParser handles the optimization log the compiler writes with
+// -gcflags=-json=0,dir, a file per source file starting with a header
+// followed by a diagnostic per line:
+//
+//	{"version":0,"package":"main","goos":"linux","goarch":"amd64","gc_version":"go1.22.0","file":"/src/main.go"}
+//	{"range":{"start":{"line":7,"character":6},"end":{"line":7,"character":6}},"severity":3,"code":"canInlineFunction","source":"go compiler","message":"cost: 5"}
+//
+// Diagnostics are translated to the messages printed by -m, so that they
+// count towards the same statistics and reports.
+type compilerJSONParser struct{}
+
+// CompilerLogHeader is the first line of a compiler optimization log.
+type CompilerLogHeader struct {
+	Version   int    `json:"version"`
+	Package   string `json:"package"`
+	GCVersion string `json:"gc_version"`
+	File      string `json:"file"`
+}
+
+// CompilerDiagnostic is an entry of a compiler optimization log, it uses
+// the LSP diagnostic structure with 1-based lines and characters.
+type CompilerDiagnostic struct {
+	Range   CompilerRange `json:"range"`
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+
+	RelatedInformation []struct {
+		Location struct {
+			URI   string        `json:"uri"`
+			Range CompilerRange `json:"range"`
+		} `json:"location"`
+		Message string `json:"message"`
+	} `json:"relatedInformation"`
+}
+
+// CompilerRange is a range of a compiler diagnostic.
+type CompilerRange struct {
+	Start CompilerPosition `json:"start"`
+	End   CompilerPosition `json:"end"`
+}
+
+// CompilerPosition is a position of a compiler diagnostic.
+type CompilerPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+func (compilerJSONParser) Detect(line []byte) bool {
+	return bytes.HasPrefix(line, []byte(`{"version":`)) && bytes.Contains(line, []byte(`"gc_version":`))
+}
+
+func (compilerJSONParser) Parse(index *Index, dir string, lines [][]byte, at int) int {
+	var header CompilerLogHeader
+	if err := json.Unmarshal(lines[at], &header); err != nil || header.File == "" {
+		return at
+	}
+
+	i := at + 1
+	for ; i < len(lines) && bytes.HasPrefix(lines[i], []byte(`{"range":`)); i++ {
+		var diagnostic CompilerDiagnostic
+		if err := json.Unmarshal(lines[i], &diagnostic); err != nil {
+			break
+		}
+		msg := compilerMessage(diagnostic.Code, diagnostic.Message)
+		if msg == "" {
+			continue
+		}
+		index.addCompilerNote(dir, header.File, diagnostic.Range, msg)
+
+		for _, related := range diagnostic.RelatedInformation {
+			// inlineLoc only tells where an inlined call came from
+			if related.Message == "inlineLoc" {
+				continue
+			}
+			path := related.Location.URI
+			if u, err := url.Parse(path); err == nil && u.Scheme == "file" {
+				path = u.Path
+			}
+			msg := strings.TrimPrefix(related.Message, "escflow:")
+			index.addCompilerNote(dir, path, related.Location.Range, msg)
+		}
+	}
+	return i - 1
+}
+
+func (index *Index) addCompilerNote(dir, path string, at CompilerRange, msg string) {
+	_, file := index.File(dir, path)
+	if !file.AddNote("compiler", at.Start.Line-1, at.Start.Character-1, []byte(msg)) {
+		return
+	}
+	file.Stats.Add([]byte(msg))
+	if at.End.Line == at.Start.Line && at.End.Character > at.Start.Character {
+		file.Notes[len(file.Notes)-1].Span = &Span{
+			Start: at.Start.Character - 1,
+			End:   at.End.Character - 1,
+		}
+	}
+}
+
+// compilerMessage returns the -m message for a diagnostic code,
+// or "" for entries that only carry related information.
+func compilerMessage(code, message string) string {
+	switch code {
+	case "canInlineFunction":
+		return "can inline function (" + message + ")"
+	case "cannotInlineFunction":
+		return "cannot inline function: " + message
+	case "cannotInlineCall":
+		return "cannot inline call: " + message
+	case "inlineCall":
+		return "inlining call to " + message
+	case "isInBounds":
+		return "Found IsInBounds"
+	case "isSliceInBounds":
+		return "Found IsSliceInBounds"
+	case "nilcheck":
+		return "generated nil check"
+	}
+	return message
+}
+
+// ReadCompilerLogs reads all files of a compiler optimization log
+// directory, as written with -gcflags=-json=0,dir.
+func ReadCompilerLogs(dir string) ([]byte, error) {
+	var names []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && filepath.Ext(path) == ".json" {
+			names = append(names, path)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var data []byte
+	for _, name := range names {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, content...)
+		data = append(data, '\n')
+	}
+	return data, nil
+}
@@ -0,0 +1,11 @@
+package main
+
+import "testing"
+
+func TestCompilerJSONParser(t *testing.T) {
+	checkParser(t, compilerJSONParser{}, `
+{"version":0,"package":"main","goos":"linux","goarch":"amd64","gc_version":"go1.22.0","file":"/src/main.go"}
+{"range":{"start":{"line":7,"character":6},"end":{"line":7,"character":6}},"severity":3,"code":"canInlineFunction","source":"go compiler","message":"cost: 5"}`, []string{
+		"main.go:7:6: compiler: can inline function (cost: 5)",
+	})
+}
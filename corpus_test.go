@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the corpus")
+
+// corpusLogs are the logs in testdata/corpus, produced from src/ with go1.27:
+//
+//	m.log          go build -gcflags=-m .
+//	m2.log         go build -gcflags=-m=2 .
+//	trimpath.log   go build -trimpath -gcflags=-m example.com/demo (in GOPATH)
+//	windows.log    m.log with backslashes and CRLF line endings
+//	vet.log        go vet .
+//	vet-json.log   go vet -json .
+var corpusLogs = []struct {
+	name string
+	tool string
+}{
+	{"m.log", ""},
+	{"m2.log", ""},
+	{"trimpath.log", ""},
+	{"windows.log", ""},
+	{"vet.log", "vet"},
+	{"vet-json.log", ""},
+}
+
+// goldenNote is a note as it is written to the golden files.
+type goldenNote struct {
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Category string `json:"category"`
+	Tool     string `json:"tool"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Count    int    `json:"count"`
+}
+
+// TestCorpus parses the logs of the corpus and compares the notes with the
+// golden files <log>.json, so that parser changes don't silently drop
+// diagnostics. With -update the golden files are rewritten, review their diff.
+func TestCorpus(t *testing.T) {
+	src, err := filepath.Abs(filepath.Join("testdata", "corpus", "src"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, log := range corpusLogs {
+		t.Run(log.name, func(t *testing.T) {
+			path := filepath.Join("testdata", "corpus", log.name)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			index := NewIndex()
+			index.Parse(src, log.tool, data)
+
+			notes := []goldenNote{}
+			for _, file := range index.SortedFiles() {
+				for _, note := range file.Notes {
+					notes = append(notes, goldenNote{
+						Path:     filepath.ToSlash(file.Path),
+						Line:     note.Line + 1,
+						Column:   note.Column + 1,
+						Category: NoteCategory(&note),
+						Tool:     note.Tool,
+						Severity: note.Severity.String(),
+						Message:  string(note.Message),
+						Count:    note.Count,
+					})
+				}
+			}
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "\t")
+			if err := enc.Encode(notes); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.Bytes()
+
+			golden := path + ".json"
+			if *updateGolden {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("notes differ from %v, run go test -run TestCorpus -update and review the diff:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// FuzzIndexAdd checks that no line of a log makes Index.Add panic or add
+// notes outside of the file.
+func FuzzIndexAdd(f *testing.F) {
+	for _, log := range corpusLogs {
+		data, err := os.ReadFile(filepath.Join("testdata", "corpus", log.name))
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, line := range SplitLines(data) {
+			f.Add(line)
+		}
+	}
+	f.Add([]byte("C:\\My Projects (x86)\\a.go:7:6: can inline f"))
+	f.Add([]byte("a:b/c.go:1: message"))
+
+	// the sources are not read, lines may name any file
+	dir := f.TempDir()
+	f.Fuzz(func(t *testing.T, line []byte) {
+		index := NewIndex()
+		index.Sources = fstest.MapFS{}
+		index.Add(dir, "compiler", line)
+		checkNotes(t, index)
+	})
+}
+
+// FuzzParse checks that no log makes Index.Parse panic or add notes
+// outside of the files.
+func FuzzParse(f *testing.F) {
+	for _, log := range corpusLogs {
+		data, err := os.ReadFile(filepath.Join("testdata", "corpus", log.name))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+
+	// the sources are not read, logs may name any file
+	dir := f.TempDir()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		index := NewIndex()
+		index.Sources = fstest.MapFS{}
+		index.Parse(dir, "", data)
+		checkNotes(t, index)
+	})
+}
+
+func checkNotes(t *testing.T, index *Index) {
+	t.Helper()
+	for key, file := range index.Files {
+		for _, note := range file.Notes {
+			if note.Line < 0 {
+				t.Errorf("%v: note %q at line %v", key, note.Message, note.Line)
+			}
+			if note.Tool == "" {
+				t.Errorf("%v: note %q without a tool", key, note.Message)
+			}
+		}
+	}
+}
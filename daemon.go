@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Daemon builds the Go modules found in a workspace and rebuilds the ones
+// whose sources change, for an always-on dashboard of all projects on a
+// developer machine.
+type Daemon struct {
+	Workspace string
+	// Command builds a module in its directory, e.g. Preset.BuildCommand.
+	Command string
+	// Poll is the interval the sources are checked for changes at.
+	Poll time.Duration
+
+	modules map[string]*daemonModule // by directory
+}
+
+// daemonModule is a module with the state of its sources at its last build.
+type daemonModule struct {
+	stamp  string
+	output []byte
+}
+
+// ParseDaemon parses the arguments of the daemon subcommand, command is the
+// build of -preset or -build run in each module.
+func ParseDaemon(args []string, command string) (*Daemon, error) {
+	set := flag.NewFlagSet("daemon", flag.ExitOnError)
+	workspace := set.String("workspace", ".", "directory to discover Go modules in")
+	poll := set.Duration("poll", 2*time.Second, "interval to check the sources for changes at")
+	set.Parse(args)
+
+	if set.NArg() > 0 {
+		return nil, errors.New("daemon: logs cannot be given, the modules are built")
+	}
+	abs, err := filepath.Abs(*workspace)
+	if err != nil {
+		return nil, err
+	}
+	return &Daemon{
+		Workspace: abs,
+		Command:   command,
+		Poll:      *poll,
+		modules:   make(map[string]*daemonModule),
+	}, nil
+}
+
+// DiscoverModules returns the directories with a go.mod file in root, except
+// hidden, vendor and testdata directories.
+func DiscoverModules(root string) ([]string, error) {
+	var modules []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// unreadable directories are skipped
+			if entry != nil && entry.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() {
+			name := entry.Name()
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Name() == "go.mod" {
+			modules = append(modules, filepath.Dir(path))
+		}
+		return nil
+	})
+	sort.Strings(modules)
+	return modules, err
+}
+
+// moduleStamp summarizes the Go sources of a module without its nested
+// modules, it changes when a file is added, removed or modified.
+func moduleStamp(module string) string {
+	files, latest := 0, time.Time{}
+	filepath.WalkDir(module, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if path == module {
+				return nil
+			}
+			if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "node_modules" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") && name != "go.mod" && name != "go.sum" && name != "go.work" {
+			return nil
+		}
+		files++
+		if info, err := entry.Info(); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return fmt.Sprintf("%d %d", files, latest.UnixNano())
+}
+
+// Update discovers the modules and builds the new ones and the ones that
+// changed since their last build. It reports whether anything changed.
+func (daemon *Daemon) Update() (bool, error) {
+	modules, err := DiscoverModules(daemon.Workspace)
+	if err != nil {
+		return false, err
+	}
+
+	changed := len(modules) != len(daemon.modules)
+	found := make(map[string]bool)
+	for _, dir := range modules {
+		found[dir] = true
+		stamp := moduleStamp(dir)
+		module := daemon.modules[dir]
+		if module != nil && module.stamp == stamp {
+			continue
+		}
+		output, err := BuildOutput(dir, daemon.Command)
+		if err != nil {
+			return false, err
+		}
+		daemon.modules[dir] = &daemonModule{stamp: stamp, output: output}
+		changed = true
+	}
+	for dir := range daemon.modules {
+		if !found[dir] {
+			delete(daemon.modules, dir)
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// Index returns an index of the last build outputs of the modules, each of
+// them a root so that paths are prefixed with its name.
+func (daemon *Daemon) Index() *Index {
+	index := newIndex()
+	dirs := make([]string, 0, len(daemon.modules))
+	for dir := range daemon.modules {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	index.Roots = append(index.Roots, dirs...)
+	for _, dir := range dirs {
+		index.Parse(dir, "", daemon.modules[dir].output)
+	}
+	return index
+}
+
+// Watch rebuilds the changed modules every Poll and serves the new index.
+func (daemon *Daemon) Watch(server *Server) {
+	ticker := time.NewTicker(daemon.Poll)
+	defer ticker.Stop()
+	for range ticker.C {
+		changed, err := daemon.Update()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "daemon: %v\n", err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		index := daemon.Index()
+		err = finishIndex(index, daemon.Workspace, daemon.Command)
+		if err == nil && server.History != nil {
+			err = server.History.Record(index)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "daemon: %v\n", err)
+			index.Close()
+			continue
+		}
+		server.replaceIndex(index)
+	}
+}
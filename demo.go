@@ -0,0 +1,37 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"path/filepath"
+)
+
+// demoFiles are the sources and logs of a sample project.
+//
+//go:embed testdata/demo
+var demoFiles embed.FS
+
+// DemoDir is the directory the sample project appears in.
+var DemoDir = filepath.FromSlash("/demo")
+
+// LoadDemo parses the logs of the embedded sample project, its sources are
+// read from the embedded files, so that the UI can be explored without
+// building anything first.
+func LoadDemo(index *Index) error {
+	files, err := fs.Sub(demoFiles, "testdata")
+	if err != nil {
+		return err
+	}
+	index.Sources = files
+	for _, log := range []struct{ tool, name string }{
+		{"", "demo/build.log"},
+		{"vet", "demo/vet.log"},
+	} {
+		data, err := fs.ReadFile(files, log.name)
+		if err != nil {
+			return err
+		}
+		index.Parse(DemoDir, log.tool, data)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// InterfaceCall is a method call through an interface.
+type InterfaceCall struct {
+	Key           string `json:"key"`
+	Path          string `json:"path"`
+	Line          int    `json:"line"` // 1 is the first line
+	Method        string `json:"method"`
+	Devirtualized bool   `json:"devirtualized"`
+}
+
+// InterfaceCalls are the calls of methods of an interface type.
+type InterfaceCalls struct {
+	Interface     string          `json:"interface"`
+	Devirtualized int             `json:"devirtualized"`
+	Calls         []InterfaceCall `json:"calls"`
+}
+
+// Devirtualization finds interface method calls in the packages of the
+// annotated Go files, marks those the compiler devirtualized and groups them
+// by interface type. Calls that are not devirtualized are candidates for
+// restructuring, e.g. by using the concrete type.
+func (index *Index) Devirtualization() []*InterfaceCalls {
+	// lines where the compiler reported devirtualization
+	devirtualized := make(map[string]map[int]bool)
+	for key, file := range index.Files {
+		if filepath.Ext(file.AbsPath) != ".go" {
+			continue
+		}
+		for _, note := range file.Notes {
+			if strings.Contains(string(note.Message), "devirtualizing") {
+				if devirtualized[key] == nil {
+					devirtualized[key] = make(map[int]bool)
+				}
+				devirtualized[key][note.Line+1] = true
+			}
+		}
+	}
+
+	byInterface := make(map[string]*InterfaceCalls)
+	for _, pkg := range index.CheckedPackages() {
+		for _, call := range pkg.interfaceCalls {
+			key := index.CanonicalPath("", call.Path)
+			result := InterfaceCall{
+				Key:           key,
+				Path:          call.Path,
+				Line:          call.Line,
+				Method:        call.Method,
+				Devirtualized: devirtualized[key][call.Line],
+			}
+			if file, ok := index.Files[key]; ok {
+				result.Path = file.Path
+			}
+
+			calls, ok := byInterface[call.Interface]
+			if !ok {
+				calls = &InterfaceCalls{Interface: call.Interface}
+				byInterface[call.Interface] = calls
+			}
+			calls.Calls = append(calls.Calls, result)
+			if result.Devirtualized {
+				calls.Devirtualized++
+			}
+		}
+	}
+
+	result := []*InterfaceCalls{}
+	for _, calls := range byInterface {
+		sort.Slice(calls.Calls, func(i, k int) bool {
+			a, b := &calls.Calls[i], &calls.Calls[k]
+			if a.Path != b.Path {
+				return a.Path < b.Path
+			}
+			if a.Line != b.Line {
+				return a.Line < b.Line
+			}
+			return a.Method < b.Method
+		})
+		result = append(result, calls)
+	}
+	sort.Slice(result, func(i, k int) bool {
+		a, b := result[i], result[k]
+		if len(a.Calls)-a.Devirtualized != len(b.Calls)-b.Devirtualized {
+			return len(a.Calls)-a.Devirtualized > len(b.Calls)-b.Devirtualized
+		}
+		return a.Interface < b.Interface
+	})
+	return result
+}
+
+func packageName(pkg *types.Package) string { return pkg.Name() }
+
+type interfaceCall struct {
+	Interface string
+	Method    string
+	Path      string
+	Line      int
+}
+
+// interfaceCalls returns the calls of interface methods in the type-checked
+// files, the calls whose types could not be resolved are missing.
+func interfaceCalls(fset *token.FileSet, files []*ast.File, info *types.Info) []interfaceCall {
+	var calls []interfaceCall
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			selection, ok := info.Selections[sel]
+			if !ok || selection.Kind() != types.MethodVal || !types.IsInterface(selection.Recv()) {
+				return true
+			}
+
+			pos := fset.Position(sel.Sel.Pos())
+			calls = append(calls, interfaceCall{
+				Interface: types.TypeString(selection.Recv(), packageName),
+				Method:    sel.Sel.Name,
+				Path:      pos.Filename,
+				Line:      pos.Line,
+			})
+			return true
+		})
+	}
+	return calls
+}
+
+// checkPackage parses the package in dir of the sources into fset and
+// type-checks it with imp, filling info. Type errors, e.g. from dependencies
+// that cannot be found, are ignored.
+func checkPackage(fset *token.FileSet, imp types.Importer, sources fs.FS, dir string, info *types.Info) []*ast.File {
+	// the files of the package are selected with the build constraints
+	ctxt := build.Default
+	ctxt.IsDir = func(path string) bool {
+		stat, err := fs.Stat(sources, SourceName(path))
+		return err == nil && stat.IsDir()
+	}
+	ctxt.ReadDir = func(dir string) ([]fs.FileInfo, error) {
+		entries, err := fs.ReadDir(sources, SourceName(dir))
+		if err != nil {
+			return nil, err
+		}
+		var infos []fs.FileInfo
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil {
+				infos = append(infos, info)
+			}
+		}
+		return infos, nil
+	}
+	ctxt.OpenFile = func(path string) (io.ReadCloser, error) {
+		return sources.Open(SourceName(path))
+	}
+	pkg, err := ctxt.ImportDir(dir, 0)
+	if err != nil {
+		return nil
+	}
+
+	var files []*ast.File
+	for _, name := range append(pkg.GoFiles, pkg.CgoFiles...) {
+		path := filepath.Join(dir, name)
+		src, err := fs.ReadFile(sources, SourceName(path))
+		if err != nil {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, src, 0)
+		if err == nil {
+			files = append(files, file)
+		}
+	}
+
+	conf := types.Config{
+		Importer: imp,
+		Error:    func(error) {},
+	}
+	conf.Check(pkg.ImportPath, fset, files, info)
+	return files
+}
@@ -0,0 +1,99 @@
+package main
+
+import "sort"
+
+// NoteChange is a note present in only one of two indexes.
+type NoteChange struct {
+	Added   bool
+	Key     string // index key of the file
+	Path    string
+	Line    int // 1 is the first line
+	Message string
+
+	fingerprint string
+}
+
+// DiffIndexes returns notes removed from old and added in new. Notes at the
+// same line are matched first, then by Fingerprint and the remaining ones
+// only by file and message, so that edits moving code around don't show up
+// as changes.
+func DiffIndexes(old, new *Index) []NoteChange {
+	oldNotes, newNotes := diffNotes(old), diffNotes(new)
+
+	byLine := func(change NoteChange) interface{} {
+		return struct {
+			key, message string
+			line         int
+		}{change.Key, change.Message, change.Line}
+	}
+	byFingerprint := func(change NoteChange) interface{} {
+		return change.fingerprint
+	}
+	byMessage := func(change NoteChange) interface{} {
+		return struct{ key, message string }{change.Key, change.Message}
+	}
+
+	oldNotes, newNotes = unmatched(oldNotes, newNotes, byLine), unmatched(newNotes, oldNotes, byLine)
+	oldNotes, newNotes = unmatched(oldNotes, newNotes, byFingerprint), unmatched(newNotes, oldNotes, byFingerprint)
+	removed, added := unmatched(oldNotes, newNotes, byMessage), unmatched(newNotes, oldNotes, byMessage)
+
+	changes := removed
+	for _, change := range added {
+		change.Added = true
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, k int) bool {
+		a, b := &changes[i], &changes[k]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Added != b.Added {
+			return !a.Added
+		}
+		return a.Message < b.Message
+	})
+	return changes
+}
+
+func diffNotes(index *Index) []NoteChange {
+	notes := []NoteChange{}
+	for key, file := range index.Files {
+		funcs := index.fileFuncs(file)
+		for i := range file.Notes {
+			note := &file.Notes[i]
+			notes = append(notes, NoteChange{
+				Key:     key,
+				Path:    file.Path,
+				Line:    note.Line + 1,
+				Message: string(note.Message),
+
+				fingerprint: Fingerprint(file.Path, funcs, note),
+			})
+		}
+	}
+	return notes
+}
+
+// unmatched returns notes in a without a counterpart in b,
+// treating notes with equal keys as a multiset.
+func unmatched(a, b []NoteChange, key func(NoteChange) interface{}) []NoteChange {
+	counts := make(map[interface{}]int)
+	for _, note := range b {
+		counts[key(note)]++
+	}
+
+	result := []NoteChange{}
+	for _, note := range a {
+		k := key(note)
+		if counts[k] > 0 {
+			counts[k]--
+			continue
+		}
+		result = append(result, note)
+	}
+	return result
+}
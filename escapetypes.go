@@ -0,0 +1,160 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EscapeSite is a heap escape of a value.
+type EscapeSite struct {
+	Key     string `json:"key"`
+	Path    string `json:"path"`
+	Line    int    `json:"line"` // 1 is the first line
+	Message string `json:"message"`
+}
+
+// EscapeType are the heap escapes of values of a type.
+type EscapeType struct {
+	Type  string       `json:"type"`
+	Sites []EscapeSite `json:"sites"`
+}
+
+// unknownType groups the escapes whose value could not be found.
+const unknownType = "(unknown)"
+
+// EscapesByType groups the heap escapes of the annotated Go files by the
+// type of the escaping value, the most frequent type first, to tell which
+// values are worth pooling. The packages are type-checked and the value is
+// the outermost expression at the position of "escapes to heap", or the
+// variable of "moved to heap:". The result is computed once per generation
+// of the index and must not be modified.
+func (index *Index) EscapesByType() []*EscapeType {
+	if index.escapeTypes != nil && index.escapeTypesGeneration == index.Generation {
+		return index.escapeTypes
+	}
+
+	values := make(map[string]map[string]valueType)
+	for _, pkg := range index.CheckedPackages() {
+		values[pkg.Dir] = pkg.values
+	}
+	byDir := make(map[string][]*File)
+	for _, file := range index.Files {
+		dir := filepath.Dir(file.AbsPath)
+		if _, ok := values[dir]; ok && file.Source == nil && filepath.Ext(file.AbsPath) == ".go" {
+			byDir[dir] = append(byDir[dir], file)
+		}
+	}
+
+	byType := make(map[string]*EscapeType)
+	for dir, files := range byDir {
+		values := values[dir]
+		for _, file := range files {
+			for _, note := range file.Notes {
+				message := string(note.Message)
+				if !strings.Contains(message, "escapes to heap") && !strings.HasPrefix(message, "moved to heap:") {
+					continue
+				}
+				typ, ok := values[positionKey(file.AbsPath, note.Line+1)+":"+strconv.Itoa(note.Column+1)].typeOf(message)
+				if !ok {
+					typ = unknownType
+				}
+				group, ok := byType[typ]
+				if !ok {
+					group = &EscapeType{Type: typ}
+					byType[typ] = group
+				}
+				group.Sites = append(group.Sites, EscapeSite{
+					Key:     file.Key,
+					Path:    file.Path,
+					Line:    note.Line + 1,
+					Message: message,
+				})
+			}
+		}
+	}
+
+	result := []*EscapeType{}
+	for _, group := range byType {
+		sort.Slice(group.Sites, func(i, k int) bool {
+			a, b := &group.Sites[i], &group.Sites[k]
+			if a.Path != b.Path {
+				return a.Path < b.Path
+			}
+			if a.Line != b.Line {
+				return a.Line < b.Line
+			}
+			return a.Message < b.Message
+		})
+		result = append(result, group)
+	}
+	sort.Slice(result, func(i, k int) bool {
+		a, b := result[i], result[k]
+		if len(a.Sites) != len(b.Sites) {
+			return len(a.Sites) > len(b.Sites)
+		}
+		return a.Type < b.Type
+	})
+	index.escapeTypes = result
+	index.escapeTypesGeneration = index.Generation
+	return result
+}
+
+// valueType is the type of a value at a position, call is the function
+// called when the position is the parenthesis of a call, where the compiler
+// also reports values of the function if it was inlined.
+type valueType struct {
+	typ  string
+	call string
+}
+
+// valueTypes returns the types of the outermost value expressions, the calls
+// and the declared variables of a type-checked package by position,
+// "path:line:column".
+func valueTypes(fset *token.FileSet, info *types.Info) map[string]valueType {
+	key := func(pos token.Pos) string {
+		position := fset.Position(pos)
+		return positionKey(position.Filename, position.Line) + ":" + strconv.Itoa(position.Column)
+	}
+
+	result := make(map[string]valueType)
+	ends := make(map[string]token.Pos)
+	for expr, tv := range info.Types {
+		if !tv.IsValue() || tv.Type == nil {
+			continue
+		}
+		typ := types.TypeString(tv.Type, packageName)
+		if call, ok := expr.(*ast.CallExpr); ok {
+			result[key(call.Lparen)] = valueType{typ, types.ExprString(call.Fun)}
+		}
+		if k := key(expr.Pos()); expr.End() > ends[k] {
+			ends[k] = expr.End()
+			if _, ok := result[k]; !ok || result[k].call == "" {
+				result[k] = valueType{typ: typ}
+			}
+		}
+	}
+	for ident, obj := range info.Defs {
+		if v, ok := obj.(*types.Var); ok {
+			result[key(ident.Pos())] = valueType{typ: types.TypeString(v.Type(), packageName)}
+		}
+	}
+	return result
+}
+
+// typeOf returns the type of the value of the escape message, false when
+// there is no value at the position or it is another one.
+func (value valueType) typeOf(message string) (string, bool) {
+	if value.typ == "" {
+		return "", false
+	}
+	if value.call == "" || strings.HasPrefix(message, value.call) || strings.HasPrefix(message, "~r") {
+		return value.typ, true
+	}
+	// e.g. make in an inlined function
+	return "", false
+}
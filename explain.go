@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Explanation describes what a compiler message means and how to fix it,
+// for developers new to escape analysis and inlining.
+type Explanation struct {
+	// Match is a substring of the messages explained.
+	Match       string `json:"match"`
+	Title       string `json:"title"`
+	Explanation string `json:"explanation"`
+	Fix         string `json:"fix,omitempty"`
+	Link        string `json:"link,omitempty"`
+}
+
+// explanations are the built-in explanations, more specific ones first.
+var explanations = []Explanation{
+	{
+		Match:       "func literal escapes to heap",
+		Title:       "Closure allocated on the heap",
+		Explanation: "The function literal outlives the call that created it, e.g. it is stored, returned or passed to a goroutine, so the closure and the variables it captures are allocated on the heap.",
+		Fix:         "Pass the captured values as arguments, or turn the closure into a method of a struct that is reused.",
+	},
+	{
+		Match:       "captured by a closure",
+		Title:       "Variable captured by a closure",
+		Explanation: "A closure refers to the variable, so it is shared with the closure instead of copied. If the closure escapes, the variable moves to the heap with it.",
+		Fix:         "Copy the value into a local variable or parameter of the closure when it doesn't need to be shared.",
+	},
+	{
+		Match:       "moved to heap:",
+		Title:       "Variable moved to the heap",
+		Explanation: "The address of the variable is used in a way the compiler cannot prove ends with the function, e.g. it is returned, stored in a global or passed to a function that keeps it, so it is allocated on the heap.",
+		Fix:         "Return or store values instead of pointers, or let the caller pass the memory to fill in.",
+		Link:        "https://go.dev/doc/faq#stack_or_heap",
+	},
+	{
+		Match:       "leaking param content:",
+		Title:       "Parameter content leaks",
+		Explanation: "Values the parameter points to are kept after the function returns, e.g. an element of a slice is stored, so callers have to allocate them on the heap.",
+		Fix:         "Copy the pointed-to values before keeping them.",
+	},
+	{
+		Match:       "leaking param:",
+		Title:       "Parameter leaks",
+		Explanation: "The pointer passed in is kept after the function returns or flows to its results, so the callers' arguments escape to the heap unless the function is inlined.",
+		Fix:         "Avoid storing the parameter, or accept a value instead of a pointer.",
+	},
+	{
+		Match:       "does not escape",
+		Title:       "No escape",
+		Explanation: "The value stays on the stack of the function, which costs no allocation. This is the result to aim for.",
+	},
+	{
+		Match:       "(interface-converted)",
+		Title:       "Interface conversion allocates",
+		Explanation: "Converting a non-pointer value to an interface copies it to the heap when the interface escapes, e.g. when it is passed to fmt.Println or stored in an any.",
+		Fix:         "Use concrete types or generics on hot paths, or pass a pointer that is already on the heap.",
+	},
+	{
+		Match:       "escapes to heap",
+		Title:       "Value escapes to the heap",
+		Explanation: "The value is allocated on the heap because it outlives the function or its size isn't known at compile time, e.g. make with a variable length or a conversion to an interface.",
+		Fix:         "Use constant sizes, preallocate buffers outside of loops, or reuse them with sync.Pool. Build with -gcflags=-m=2 to see why the value escapes.",
+		Link:        "https://go.dev/doc/gc-guide#Eliminating_heap_allocations",
+	},
+	{
+		Match:       "function too complex",
+		Title:       "Function too large to inline",
+		Explanation: "The inlining cost of the function, roughly the number of nodes of its syntax tree, exceeds the budget of 80, so calls to it are not inlined.",
+		Fix:         "Move rarely executed code, e.g. error handling, into a separate function so that the fast path fits the budget. Use the inline-budget subcommand to see how far over budget it is.",
+		Link:        "https://go.dev/wiki/CompilerOptimizations#function-inlining",
+	},
+	{
+		Match:       "recursive",
+		Title:       "Recursive function",
+		Explanation: "Recursive functions are not inlined, as inlining them would never end.",
+		Fix:         "Rewrite the recursion as a loop when the function is hot.",
+	},
+	{
+		Match:       "marked go:noinline",
+		Title:       "Inlining disabled",
+		Explanation: "The function has a //go:noinline directive.",
+		Fix:         "Remove the directive if it was only needed for a benchmark.",
+	},
+	{
+		Match:       "cannot inline",
+		Title:       "Function not inlined",
+		Explanation: "The function cannot be inlined, the reason follows the name: some statements, e.g. select, defer in older Go versions or calls through go:linkname, prevent inlining regardless of the cost.",
+		Fix:         "Move the statements that prevent inlining into a separate function.",
+		Link:        "https://go.dev/wiki/CompilerOptimizations#function-inlining",
+	},
+	{
+		Match:       "inlining call to",
+		Title:       "Call inlined",
+		Explanation: "The body of the called function is copied into the caller, which saves the call and lets escape analysis see through it.",
+	},
+	{
+		Match:       "can inline",
+		Title:       "Inlinable function",
+		Explanation: "The function is within the inlining budget, calls to it may be inlined.",
+	},
+	{
+		Match:       "Found IsSliceInBounds",
+		Title:       "Slice bounds check",
+		Explanation: "Slicing needs a check that the indices are within the capacity, which could not be proven statically.",
+		Fix:         "Slice once before a loop, e.g. s = s[:n], so that the compiler can prove the later bounds.",
+	},
+	{
+		Match:       "Found IsInBounds",
+		Title:       "Index bounds check",
+		Explanation: "Indexing needs a check that the index is within the length, which could not be proven statically. A failing check panics.",
+		Fix:         "Add an early check of the largest index, e.g. _ = b[7] before reading b[0] to b[7], or iterate with range.",
+	},
+}
+
+// Explain returns the first explanation matching message, nil when there
+// is none.
+func Explain(list []Explanation, message string) *Explanation {
+	for i := range list {
+		if strings.Contains(message, list[i].Match) {
+			return &list[i]
+		}
+	}
+	return nil
+}
+
+// serveExplain responds with the explanation of ?message=.
+func (server *Server) serveExplain(w http.ResponseWriter, r *http.Request) {
+	explanation := Explain(server.explanations(), r.FormValue("message"))
+	if explanation == nil {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "No explanation.")
+		return
+	}
+	writeJSON(w, explanation)
+}
+
+func (server *Server) explanations() []Explanation {
+	if server.Explanations == nil {
+		return explanations
+	}
+	return server.Explanations
+}
+
+// ParseExplanations parses a YAML list of explanations. Only the subset of
+// YAML needed for it is supported: a list of mappings with plain, quoted,
+// literal (|) and folded (>) strings.
+//
+//   - match: "escapes to heap: flow"
+//     title: Escape flow
+//     explanation: |
+//     Printed with -m=2, the following lines show how the value escapes.
+func ParseExplanations(data []byte) ([]Explanation, error) {
+	var list []Explanation
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), " \t\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for n := 0; n < len(lines); n++ {
+		line := lines[n]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "- ") {
+			list = append(list, Explanation{})
+			line = "  " + line[2:]
+		} else if !strings.HasPrefix(line, "  ") || len(list) == 0 {
+			return nil, fmt.Errorf("line %d: expected a list of explanations", n+1)
+		}
+
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("line %d: expected key: value", n+1)
+		}
+		key, value := strings.TrimSpace(line[:colon]), strings.TrimSpace(line[colon+1:])
+		if value == "|" || value == ">" {
+			var block []string
+			for n+1 < len(lines) && (strings.HasPrefix(lines[n+1], "    ") || strings.TrimSpace(lines[n+1]) == "") {
+				n++
+				block = append(block, strings.TrimPrefix(lines[n], "    "))
+			}
+			separator := "\n"
+			if value == ">" {
+				separator = " "
+			}
+			value = strings.TrimSpace(strings.Join(block, separator))
+		} else {
+			var err error
+			if value, err = yamlScalar(value); err != nil {
+				return nil, fmt.Errorf("line %d: %v", n+1, err)
+			}
+		}
+
+		explanation := &list[len(list)-1]
+		switch key {
+		case "match":
+			explanation.Match = value
+		case "title":
+			explanation.Title = value
+		case "explanation":
+			explanation.Explanation = value
+		case "fix":
+			explanation.Fix = value
+		case "link":
+			explanation.Link = value
+		default:
+			return nil, fmt.Errorf("line %d: unknown key %q, expected match, title, explanation, fix or link", n+1, key)
+		}
+	}
+
+	for i, explanation := range list {
+		if explanation.Match == "" {
+			return nil, fmt.Errorf("explanation %d: match is required", i+1)
+		}
+	}
+	return list, nil
+}
+
+// yamlScalar returns the string of a plain or quoted YAML scalar.
+func yamlScalar(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		return strconv.Unquote(value)
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", errors.New("unterminated string")
+		}
+		return strings.Replace(value[1:len(value)-1], "''", "'", -1), nil
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value, nil
+}
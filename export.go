@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Embedded is the data of all files included in a self-contained report.
+type Embedded struct {
+	Files map[string]*AnnotatedFile `json:"files"` // by index key
+	Dirs  map[string][]string       `json:"dirs"`  // file keys by directory
+}
+
+// ExportHTML writes the index page with all files and their annotations
+// embedded, so that it can be opened without the server. Sources are left
+// out when sources is false.
+func (server *Server) ExportHTML(w io.Writer, sources bool) error {
+	embedded := &Embedded{
+		Files: make(map[string]*AnnotatedFile),
+		Dirs:  make(map[string][]string),
+	}
+	for key, file := range server.Index.Files {
+		annotated, err := server.Index.LoadAnnotatedFile(context.Background(), key, Filter{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", file.Path, err)
+			continue
+		}
+		if !sources {
+			for i := range annotated.Lines {
+				annotated.Lines[i].Source = ""
+			}
+		}
+		embedded.Files[key] = annotated
+		if file.Source == nil {
+			dir := filepath.Dir(file.AbsPath)
+			embedded.Dirs[dir] = append(embedded.Dirs[dir], key)
+		}
+	}
+
+	for _, keys := range embedded.Dirs {
+		sort.Strings(keys)
+	}
+
+	page := server.indexPage()
+	page.Embedded = embedded
+	page.Logs = false
+	page.History = false
+	return server.Template.ExecuteTemplate(w, "index.html", page)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// fingerprintNumbers are replaced in messages, so that fingerprints don't
+// change with costs, positions or closure numbers, e.g. "main.func2".
+var fingerprintNumbers = regexp.MustCompile(`[0-9]+`)
+
+// Fingerprint identifies a note across edits of its file. It combines the
+// path, the enclosing function, the tool, the message without numbers and
+// the line relative to the start of the function, so that adding a line
+// above the function doesn't change it. funcs are the functions of the file,
+// see fileFuncs.
+func Fingerprint(path string, funcs []funcRange, note *Note) string {
+	line := note.Line + 1
+	fn := ""
+	for _, f := range funcs {
+		if f.From <= line && line <= f.To {
+			fn, line = f.Name, line-f.From
+			break
+		}
+	}
+
+	hash := fnv.New64a()
+	for _, part := range []string{
+		filepath.ToSlash(path), fn, note.Tool,
+		fingerprintNumbers.ReplaceAllString(string(note.Message), "0"),
+		strconv.Itoa(line),
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+// fileFuncs returns the functions of a Go file for Fingerprint,
+// nothing for other files.
+func (index *Index) fileFuncs(file *File) []funcRange {
+	if file.Source != nil || filepath.Ext(file.AbsPath) != ".go" {
+		return nil
+	}
+	_, funcs := index.goFuncs(file.AbsPath)
+	return funcs
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// followIdle is how long the input has to be quiet before the lines read so
+// far are parsed, so that multi-line reports are usually parsed at once.
+const followIdle = 200 * time.Millisecond
+
+// followMaxPending forces parsing of a continuously written input.
+const followMaxPending = 1 << 20
+
+// Follow parses inputs incrementally while the server is running, so that
+// the annotations of a long build show up while it is still printing them.
+func (server *Server) Follow(dir string, inputs []string) {
+	for _, input := range inputs {
+		tool, name := SplitInput(input)
+		if err := server.follow(dir, tool, name); err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", name, err)
+		}
+	}
+}
+
+func (server *Server) follow(dir string, tool string, name string) error {
+	var rd io.Reader = os.Stdin
+	if name != "" {
+		file, err := OpenInput(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		rd = file
+	}
+	return server.followReader(dir, tool, rd)
+}
+
+// followReader parses the lines read from rd until it ends.
+func (server *Server) followReader(dir string, tool string, rd io.Reader) error {
+	lines := make(chan []byte)
+	done := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(rd)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				lines <- line
+			}
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				done <- err
+				close(lines)
+				return
+			}
+		}
+	}()
+
+	var pending []byte
+	idle := time.NewTimer(followIdle)
+	defer idle.Stop()
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				server.Parse(dir, tool, pending)
+				return <-done
+			}
+			pending = append(pending, line...)
+			if len(pending) >= followMaxPending {
+				server.Parse(dir, tool, pending)
+				pending = nil
+			}
+			idle.Reset(followIdle)
+		case <-idle.C:
+			if len(pending) > 0 {
+				server.Parse(dir, tool, pending)
+				pending = nil
+			}
+		}
+	}
+}
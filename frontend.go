@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+)
+
+func init() {
+	RegisterParser(110, tscParser{})
+	RegisterParser(120, eslintParser{})
+}
+
+// tscParser handles the output of tsc --pretty false:
+//
+//	src/app.ts(12,5): error TS2322: Type 'string' is not assignable to type 'number'.
+//
+// Notes are labeled "tsc" and categorized by the error code.
+type tscParser struct{}
+
+func (tscParser) Detect(line []byte) bool {
+	_, ok := parseTSC(line)
+	return ok
+}
+
+func (tscParser) Parse(index *Index, dir string, lines [][]byte, at int) int {
+	diagnostic, _ := parseTSC(lines[at])
+	_, file := index.File(dir, diagnostic.Path)
+	file.AddStructuredNote(diagnostic.Note)
+	return at
+}
+
+type frontendDiagnostic struct {
+	Path string
+	Note Note
+}
+
+var tscSeverity = map[string]Severity{
+	"error":      Error,
+	"warning":    Warning,
+	"suggestion": Info,
+	"message":    Info,
+}
+
+func parseTSC(line []byte) (diagnostic frontendDiagnostic, ok bool) {
+	open := bytes.Index(line, []byte("("))
+	end := bytes.Index(line, []byte("): "))
+	if open <= 0 || end < open {
+		return diagnostic, false
+	}
+	// the path may contain parentheses, the position is the last ones
+	open = bytes.LastIndexByte(line[:end], '(')
+	comma := bytes.IndexByte(line[open:end], ',')
+	if comma < 0 {
+		return diagnostic, false
+	}
+	lineno, ok1 := ParseInt(line[open+1 : open+comma])
+	column, ok2 := ParseInt(line[open+comma+1 : end])
+	if !ok1 || !ok2 {
+		return diagnostic, false
+	}
+
+	msg := line[end+3:]
+	space := bytes.IndexByte(msg, ' ')
+	if space < 0 {
+		return diagnostic, false
+	}
+	severity, known := tscSeverity[string(msg[:space])]
+	code := msg[space+1:]
+	colon := bytes.Index(code, []byte(": "))
+	if !known || colon < 0 || !bytes.HasPrefix(code, []byte("TS")) {
+		return diagnostic, false
+	}
+
+	diagnostic.Path = string(line[:open])
+	diagnostic.Note = Note{
+		Line:     lineno - 1,
+		Column:   column - 1,
+		Message:  append(append(append([]byte{}, msg[:space]...), ": "...), code[colon+2:]...),
+		Tool:     "tsc",
+		Severity: severity,
+		Category: string(code[:colon]),
+	}
+	return diagnostic, true
+}
+
+// eslintParser handles the output of eslint -f unix, the plain format with
+// the severity and rule appended:
+//
+//	/src/app.js:12:5: Missing semicolon. [Error/semi]
+//
+// Notes are labeled "eslint" and categorized by the rule.
+type eslintParser struct{}
+
+func (eslintParser) Detect(line []byte) bool {
+	_, ok := parseESLint(line)
+	return ok
+}
+
+func (eslintParser) Parse(index *Index, dir string, lines [][]byte, at int) int {
+	diagnostic, _ := parseESLint(lines[at])
+	_, file := index.File(dir, diagnostic.Path)
+	file.AddStructuredNote(diagnostic.Note)
+	return at
+}
+
+var eslintSeverity = map[string]Severity{
+	"Error":   Error,
+	"Warning": Warning,
+}
+
+func parseESLint(line []byte) (diagnostic frontendDiagnostic, ok bool) {
+	if !bytes.HasSuffix(line, []byte("]")) {
+		return diagnostic, false
+	}
+	path, lineno, column, msg, ok := ParseFileLine(line)
+	open := bytes.LastIndex(msg, []byte(" ["))
+	if !ok || open < 0 {
+		return diagnostic, false
+	}
+	label := msg[open+2 : len(msg)-1]
+	slash := bytes.IndexByte(label, '/')
+	if slash < 0 {
+		// rules can be missing, e.g. for parsing errors
+		slash = len(label)
+	}
+	severity, known := eslintSeverity[string(label[:slash])]
+	if !known {
+		return diagnostic, false
+	}
+
+	diagnostic.Path = string(path)
+	diagnostic.Note = Note{
+		Line:     lineno - 1,
+		Column:   column - 1,
+		Message:  msg[:open],
+		Tool:     "eslint",
+		Severity: severity,
+	}
+	if slash < len(label) {
+		diagnostic.Note.Category = string(label[slash+1:])
+	}
+	return diagnostic, true
+}
@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestTscParser(t *testing.T) {
+	checkParser(t, tscParser{}, `
+src/app.ts(12,5): error TS2322: Type 'string' is not assignable to type 'number'.`, []string{
+		"src/app.ts:12:5: tsc: error: Type 'string' is not assignable to type 'number'.",
+	})
+}
+
+func TestEslintParser(t *testing.T) {
+	checkParser(t, eslintParser{}, `
+/src/app.js:12:5: Missing semicolon. [Error/semi]`, []string{
+		"app.js:12:5: eslint: Missing semicolon.",
+	})
+}
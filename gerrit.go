@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// gerritRequest sends a request to the REST API of the Gerrit server at host
+// and returns the body of the response without the line preventing
+// cross-site script inclusion. With a user, the request is authenticated
+// with its HTTP password.
+func gerritRequest(method, host, path, user, password string, body interface{}) ([]byte, error) {
+	if user != "" {
+		path = "/a" + path
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, host+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if user != "" {
+		req.SetBasicAuth(user, password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%v %v: %v: %s", method, req.URL, resp.Status, bytes.TrimSpace(data))
+	}
+	return bytes.TrimPrefix(data, []byte(")]}'")), nil
+}
+
+// gerritRobotComment is a RobotCommentInput of the Gerrit REST API.
+type gerritRobotComment struct {
+	RobotID    string `json:"robot_id"`
+	RobotRunID string `json:"robot_run_id"`
+	Line       int    `json:"line"`
+	Message    string `json:"message"`
+}
+
+// PublishGerrit implements the publish-gerrit subcommand: it posts the
+// annotations added between two logs as robot comments on a patch set of a
+// Gerrit change. Annotations in files the patch set doesn't touch are left
+// out, Gerrit rejects comments on them.
+func PublishGerrit(args []string, w io.Writer) error {
+	set := flag.NewFlagSet("publish-gerrit", flag.ExitOnError)
+	change := set.String("change", "", "URL of the change, e.g. https://gerrit.example.com/c/project/+/1234")
+	patchSet := set.String("patchset", "current", "patch set to comment on")
+	oldLog := set.String("old-log", "", "compiler log before the change")
+	newLog := set.String("new-log", "", "compiler log of the patch set")
+	robotID := set.String("robot-id", "view-annotated-file", "robot id of the comments")
+	runID := set.String("run-id", strconv.FormatInt(time.Now().Unix(), 10), "robot run id of the comments, e.g. the CI build")
+	max := set.Int("max", 50, "maximum number of comments")
+	dryRun := set.Bool("dry-run", false, "print the review instead of posting it")
+	set.Parse(args)
+
+	if *change == "" || *oldLog == "" || *newLog == "" {
+		return errors.New("publish-gerrit: -change, -old-log and -new-log must be specified")
+	}
+	match := gerritChangeURL.FindStringSubmatch(*change)
+	if match == nil {
+		return fmt.Errorf("publish-gerrit: %q is not the URL of a Gerrit change", *change)
+	}
+	host, number := match[1], match[2]
+	user, password := os.Getenv("GERRIT_USER"), os.Getenv("GERRIT_PASSWORD")
+	revision := "/changes/" + number + "/revisions/" + url.PathEscape(*patchSet)
+
+	dir, _ := filepath.Abs(".")
+	root := repoRoot(dir)
+	oldIndex, newIndex := NewIndex(), NewIndex()
+	for _, log := range []struct {
+		index *Index
+		name  string
+	}{{oldIndex, *oldLog}, {newIndex, *newLog}} {
+		data, err := ReadInput(log.name)
+		if err != nil {
+			return fmt.Errorf("%v: %v", log.name, err)
+		}
+		log.index.Parse(dir, "", data)
+	}
+
+	data, err := gerritRequest(http.MethodGet, host, revision+"/files", user, password, nil)
+	if err != nil {
+		return err
+	}
+	var files map[string]json.RawMessage
+	if err := json.Unmarshal(data, &files); err != nil {
+		return err
+	}
+
+	comments := make(map[string][]gerritRobotComment)
+	count, skipped := 0, 0
+	for _, note := range DiffIndexes(oldIndex, newIndex) {
+		if !note.Added {
+			continue
+		}
+		path, err := repoPath(root, newIndex.Files[note.Key].AbsPath)
+		if err != nil || files[path] == nil || count == *max {
+			skipped++
+			continue
+		}
+		comments[path] = append(comments[path], gerritRobotComment{
+			RobotID:    *robotID,
+			RobotRunID: *runID,
+			Line:       note.Line,
+			Message:    note.Message,
+		})
+		count++
+	}
+
+	review := struct {
+		Tag           string                          `json:"tag"`
+		Message       string                          `json:"message"`
+		RobotComments map[string][]gerritRobotComment `json:"robot_comments"`
+	}{
+		Tag:           "autogenerated:" + *robotID,
+		Message:       fmt.Sprintf("%d new annotations", count),
+		RobotComments: comments,
+	}
+	if *dryRun {
+		data, err := json.MarshalIndent(review, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "POST %s%s/review\n%s\n", host, revision, data)
+		return nil
+	}
+	if count == 0 {
+		fmt.Fprintf(w, "No new annotations in the files of the patch set.\n")
+		return nil
+	}
+	if _, err := gerritRequest(http.MethodPost, host, revision+"/review", user, password, review); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Posted %d comments, %d annotations skipped.\n", count, skipped)
+	return nil
+}
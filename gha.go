@@ -0,0 +1,124 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// FetchGHA downloads a named artifact of a GitHub Actions workflow run
+// and returns the concatenated content of the files inside it.
+func FetchGHA(args []string) ([]byte, error) {
+	set := flag.NewFlagSet("fetch-gha", flag.ExitOnError)
+	repo := set.String("repo", "", "repository as owner/name")
+	run := set.String("run", "", "workflow run id")
+	artifact := set.String("artifact", "", "artifact name")
+	token := set.String("token", os.Getenv("GITHUB_TOKEN"), "GitHub token, defaults to $GITHUB_TOKEN")
+	set.Parse(args)
+
+	if *repo == "" || *run == "" || *artifact == "" {
+		return nil, errors.New("fetch-gha: -repo, -run and -artifact must be specified")
+	}
+
+	api := os.Getenv("GITHUB_API_URL")
+	if api == "" {
+		api = "https://api.github.com"
+	}
+
+	var list struct {
+		Artifacts []struct {
+			Name        string `json:"name"`
+			Expired     bool   `json:"expired"`
+			DownloadURL string `json:"archive_download_url"`
+		} `json:"artifacts"`
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/actions/runs/%s/artifacts?per_page=100", api, *repo, *run)
+	data, err := githubGet(url, *token)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	for _, a := range list.Artifacts {
+		if a.Name != *artifact {
+			continue
+		}
+		if a.Expired {
+			return nil, fmt.Errorf("fetch-gha: artifact %q has expired", *artifact)
+		}
+
+		archive, err := githubGet(a.DownloadURL, *token)
+		if err != nil {
+			return nil, err
+		}
+		return unzipAll(archive)
+	}
+
+	return nil, fmt.Errorf("fetch-gha: artifact %q not found in run %v", *artifact, *run)
+}
+
+func githubGet(url string, token string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %v: %v", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// unzipAll concatenates all files in a zip archive,
+// decompressing them when needed.
+func unzipAll(archive []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, err
+	}
+
+	var all []byte
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		rd, err := Decompress(rc)
+		if err != nil {
+			rc.Close()
+			return nil, err
+		}
+		data, err := io.ReadAll(rd)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, data...)
+		all = append(all, '\n')
+	}
+	return all, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// goVersionPattern matches Go versions in logs and GOROOT paths, e.g.
+// "go version go1.21.5 linux/amd64", "gc_version":"go1.21.5" or
+// /opt/hostedtoolcache/go/1.21.5/x64/src.
+var goVersionPattern = regexp.MustCompile(`(?:go version go|"gc_version":"go|/sdk/go|/go/)(1\.\d+(?:\.\d+)?)\b`)
+
+// DetectGoVersion returns the Go version mentioned in a log, e.g. "go1.21.5",
+// or "" when there is none.
+func DetectGoVersion(data []byte) string {
+	if m := goVersionPattern.FindSubmatch(data); m != nil {
+		return "go" + string(m[1])
+	}
+	return ""
+}
+
+// Goroot returns the GOROOT with the sources of the Go version the logs were
+// built with: the local one when the versions match or the version is
+// unknown, otherwise one installed by golang.org/dl in ~/sdk, which is
+// downloaded with -download-goroot.
+func (index *Index) Goroot() string {
+	if index.goroot != nil {
+		return *index.goroot
+	}
+	goroot := localGoroot()
+	index.goroot = &goroot
+	if index.GoVersion == "" || index.GoVersion == gorootVersion(goroot) {
+		return goroot
+	}
+
+	home, _ := os.UserHomeDir()
+	sdk := filepath.Join(home, "sdk", index.GoVersion)
+	if _, err := os.Stat(sdk); err != nil && *downloadGoroot {
+		fmt.Fprintf(os.Stderr, "downloading %v sources\n", index.GoVersion)
+		if err := downloadGo(index.GoVersion); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+	if _, err := os.Stat(sdk); err == nil {
+		goroot = sdk
+	} else {
+		fmt.Fprintf(os.Stderr, "the logs are from %v, showing standard library sources of %v\n", index.GoVersion, gorootVersion(goroot))
+	}
+	index.goroot = &goroot
+	return goroot
+}
+
+func localGoroot() string {
+	if out, err := exec.Command("go", "env", "GOROOT").Output(); err == nil {
+		return strings.TrimSpace(string(out))
+	}
+	return runtime.GOROOT()
+}
+
+// gorootVersion returns the version of the Go installation at goroot.
+func gorootVersion(goroot string) string {
+	data, err := os.ReadFile(filepath.Join(goroot, "VERSION"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0])
+}
+
+// downloadGo installs the Go version to ~/sdk with golang.org/dl.
+func downloadGo(version string) error {
+	cmd := exec.Command("go", "run", "golang.org/dl/"+version+"@latest", "download")
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("downloading %v: %v", version, err)
+	}
+	return nil
+}
+
+// stdlibPath translates paths of standard library files with GorootPath,
+// paths in the current directory are returned unchanged.
+func (index *Index) stdlibPath(dir string, path string) string {
+	if translated, ok := index.stdlibPaths[path]; ok {
+		return translated
+	}
+	if index.stdlibPaths == nil {
+		index.stdlibPaths = make(map[string]string)
+	}
+	translated := path
+	if !exists(dir, path) && !strings.HasPrefix(path, "./") && !strings.HasPrefix(path, "."+string(filepath.Separator)) {
+		translated = GorootPath(index.Goroot(), dir, path)
+	}
+	index.stdlibPaths[path] = translated
+	return translated
+}
+
+// GorootPath translates a path of a standard library file that doesn't
+// exist locally to the file in goroot:
+//
+//	/opt/hostedtoolcache/go/1.21.5/x64/src/fmt/print.go -> goroot/src/fmt/print.go
+//	$GOROOT/src/fmt/print.go                             -> goroot/src/fmt/print.go
+//	fmt/print.go (built with -trimpath)                  -> goroot/src/fmt/print.go
+//
+// Other paths are returned unchanged.
+func GorootPath(goroot string, dir string, path string) string {
+	if exists(dir, path) {
+		return path
+	}
+
+	slashed := filepath.ToSlash(path)
+	candidates := []string{slashed}
+	for p := strings.Index(slashed, "/src/"); p >= 0; {
+		candidates = append(candidates, slashed[p+len("/src/"):])
+		next := strings.Index(slashed[p+1:], "/src/")
+		if next < 0 {
+			break
+		}
+		p += 1 + next
+	}
+	for _, rel := range candidates {
+		if rel == "" || filepath.IsAbs(rel) || strings.HasPrefix(rel, ".") {
+			continue
+		}
+		translated := filepath.Join(goroot, "src", filepath.FromSlash(rel))
+		if exists("", translated) {
+			return translated
+		}
+	}
+	return path
+}
+
+// exists reports whether the file at path, relative to dir, exists.
+func exists(dir string, path string) bool {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
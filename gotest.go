@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// GoTest runs go test with the compiler diagnostics enabled and without
+// running any tests, so that test files and test-only packages are
+// annotated as well. Arguments are passed to go test, e.g. packages or
+// -gcflags overriding the default "all=-m".
+func (server *Server) GoTest(dir string, args []string) error {
+	args = goTestArgs(args)
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	rd, w := io.Pipe()
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	filtered, filter := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(rd)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			if !IsTestChatter(scanner.Bytes()) {
+				filter.Write(append(scanner.Bytes(), '\n'))
+			}
+		}
+		filter.CloseWithError(scanner.Err())
+	}()
+	go func() {
+		w.CloseWithError(cmd.Wait())
+	}()
+
+	err := server.followReader(dir, "", filtered)
+	if _, failed := err.(*exec.ExitError); failed {
+		// build failures are annotated like other diagnostics
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("go %s: %v", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// goTestArgs returns the arguments of the go command run by GoTest.
+func goTestArgs(args []string) []string {
+	if len(args) == 0 {
+		args = []string{"./..."}
+	}
+	return append([]string{"test", "-run=NONE", "-gcflags=all=-m"}, args...)
+}
+
+// testChatter are prefixes of the lines go test prints about packages and
+// tests rather than about the source.
+var testChatter = [...]string{
+	"ok  \t", "?   \t", "FAIL\t", "--- ", "=== ",
+	"testing: warning: no tests to run",
+}
+
+// IsTestChatter reports whether line is printed by go test about the test
+// run rather than by the compiler.
+func IsTestChatter(line []byte) bool {
+	if string(line) == "PASS" || string(line) == "FAIL" {
+		return true
+	}
+	for _, prefix := range testChatter {
+		if bytes.HasPrefix(line, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxHistoryRuns limits the runs loaded from the history directory, the
+// most recent ones are kept.
+const maxHistoryRuns = 100
+
+// Run are the annotations of one recorded index.
+type Run struct {
+	Metadata Metadata  `json:"metadata"`
+	Notes    []RunNote `json:"notes"`
+}
+
+// RunNote is an annotation of a run, identified by its Fingerprint.
+type RunNote struct {
+	Path        string `json:"path"`
+	Line        int    `json:"line"` // 1 is the first line
+	Tool        string `json:"tool"`
+	Message     string `json:"message"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// History is a directory of recorded runs, the trend store enabled with
+// -history. A run of a known commit replaces earlier runs of the commit.
+type History struct {
+	Dir string
+
+	// mu guards runs, sorted by time.
+	mu   sync.Mutex
+	runs []*Run
+}
+
+// OpenHistory loads the runs recorded in dir, which is created when missing.
+func OpenHistory(dir string) (*History, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	history := &History{Dir: dir}
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		run := &Run{}
+		if err := json.Unmarshal(data, run); err != nil {
+			return nil, fmt.Errorf("%v: %v", name, err)
+		}
+		history.runs = append(history.runs, run)
+	}
+	history.sort()
+	return history, nil
+}
+
+// Record saves the annotations of index as a run.
+func (history *History) Record(index *Index) error {
+	run := &Run{Metadata: index.Metadata, Notes: []RunNote{}}
+	if run.Metadata.Time.IsZero() {
+		run.Metadata.Time = time.Now()
+	}
+	for _, file := range index.SortedFiles() {
+		funcs := index.fileFuncs(file)
+		for i := range file.Notes {
+			note := &file.Notes[i]
+			run.Notes = append(run.Notes, RunNote{
+				Path:        file.Path,
+				Line:        note.Line + 1,
+				Tool:        note.Tool,
+				Message:     string(note.Message),
+				Fingerprint: Fingerprint(file.Path, funcs, note),
+			})
+		}
+	}
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("run-%020d.json", run.Metadata.Time.UnixNano())
+	if run.Metadata.Commit != "" {
+		name = "commit-" + run.Metadata.Commit + ".json"
+	}
+	if err := os.WriteFile(filepath.Join(history.Dir, name), data, 0644); err != nil {
+		return err
+	}
+
+	history.mu.Lock()
+	defer history.mu.Unlock()
+	runs := history.runs[:0]
+	for _, recorded := range history.runs {
+		if run.Metadata.Commit == "" || recorded.Metadata.Commit != run.Metadata.Commit {
+			runs = append(runs, recorded)
+		}
+	}
+	history.runs = append(runs, run)
+	history.sort()
+	return nil
+}
+
+// sort orders the runs by time and drops the oldest beyond maxHistoryRuns.
+func (history *History) sort() {
+	sort.SliceStable(history.runs, func(i, k int) bool {
+		return history.runs[i].Metadata.Time.Before(history.runs[k].Metadata.Time)
+	})
+	if len(history.runs) > maxHistoryRuns {
+		history.runs = history.runs[len(history.runs)-maxHistoryRuns:]
+	}
+}
+
+// LineHistory is the history of the annotations of a line across the
+// recorded runs, oldest first.
+type LineHistory struct {
+	Path  string        `json:"path"`
+	Line  int           `json:"line"`
+	Runs  []Metadata    `json:"runs"`
+	Notes []NoteHistory `json:"notes"`
+}
+
+// NoteHistory tells in which runs an annotation of the line was present.
+type NoteHistory struct {
+	Tool    string `json:"tool"`
+	Message string `json:"message"`
+	// Present has an element for each run.
+	Present []bool `json:"present"`
+	// Since is the index of the first run of the latest streak of runs
+	// with the annotation, -1 when the latest run doesn't have it.
+	Since int `json:"since"`
+}
+
+// Line returns the history of the annotations of a line of file in index,
+// matched across runs by Fingerprint.
+func (history *History) Line(index *Index, file *File, line int) *LineHistory {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+
+	result := &LineHistory{Path: file.Path, Line: line, Runs: []Metadata{}, Notes: []NoteHistory{}}
+	for _, run := range history.runs {
+		result.Runs = append(result.Runs, run.Metadata)
+	}
+
+	funcs := index.fileFuncs(file)
+	seen := make(map[string]bool)
+	for i := range file.Notes {
+		note := &file.Notes[i]
+		fingerprint := Fingerprint(file.Path, funcs, note)
+		if note.Line+1 != line || seen[fingerprint] {
+			continue
+		}
+		seen[fingerprint] = true
+
+		noteHistory := NoteHistory{Tool: note.Tool, Message: string(note.Message), Since: -1}
+		for i, run := range history.runs {
+			present := run.has(fingerprint)
+			noteHistory.Present = append(noteHistory.Present, present)
+			if !present {
+				noteHistory.Since = -1
+			} else if noteHistory.Since < 0 {
+				noteHistory.Since = i
+			}
+		}
+		result.Notes = append(result.Notes, noteHistory)
+	}
+	return result
+}
+
+func (run *Run) has(fingerprint string) bool {
+	for i := range run.Notes {
+		if run.Notes[i].Fingerprint == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// serveHistory responds with the history of ?path= and ?line=.
+func (server *Server) serveHistory(w http.ResponseWriter, r *http.Request) {
+	if server.History == nil {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "No history recorded, see -history.")
+		return
+	}
+	file, ok := server.Index.Lookup(r.FormValue("path"))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "Unknown file %q.", r.FormValue("path"))
+		return
+	}
+	line, err := strconv.Atoi(r.FormValue("line"))
+	if err != nil || line < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid line %q.", r.FormValue("line"))
+		return
+	}
+	writeJSON(w, server.History.Line(server.Index, file, line))
+}
@@ -2,47 +2,263 @@ package main
 
 import (
 	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Index struct {
+	// Generation is incremented every time the index changes.
+	Generation int
+	Modified   time.Time
+
 	Files map[string]*File
+	Races []*Race
+
+	Goroutines []*Goroutine
+
+	// Allocated is bytes allocated per file key and line from a heap profile.
+	Allocated map[string]map[int]int64
+
+	// canonical caches CanonicalPath, which is also called by lookups of
+	// concurrent requests, so it is guarded by canonicalMu.
+	canonicalMu sync.Mutex
+	canonical   map[string]string
+
+	testFiles map[string][]string
+
+	// lineDirectives are //line directives of Go files by index key.
+	lineDirectives map[string][]LineDirective
+	// autogenerated are lines of the autogenerated file by symbol.
+	autogenerated     map[string]int
+	autogeneratedLast string
+
+	// Roots are the checkouts relative paths are resolved against,
+	// when empty they are resolved against the directory of the log.
+	Roots []string
+	// roots are the roots of the paths seen so far.
+	roots map[string]string
+
+	// GoVersion is the version of Go the logs were built with, e.g. "go1.21.5",
+	// used to find the matching standard library sources.
+	GoVersion string
+	// goroot is the GOROOT standard library paths are resolved against,
+	// found on the first use.
+	goroot *string
+	// stdlibPaths caches translated standard library paths.
+	stdlibPaths map[string]string
+
+	// modcache is the Go module cache, found on the first use.
+	modcache *string
+
+	// bazelWorkspace is the Bazel workspace paths are translated for,
+	// found on the first use.
+	bazelWorkspace *string
+
+	// Metadata describes the build the logs come from.
+	Metadata Metadata
+
+	// Unparsed are the log lines no parser understood.
+	Unparsed UnparsedLines
+	// Logs are the parsed logs, in the order of parsing.
+	Logs []Log
+	// CodeOwners group the files by owner, nil when there are none.
+	CodeOwners *CodeOwners
+	// Weights rank the annotations of hot packages first, nil weighs all
+	// packages the same.
+	Weights *Weights
+
+	// Limits restrict how much of a file is loaded for the viewer.
+	Limits Limits
+
+	// Sources is the file system the sources are read from, see SourceName,
+	// nil is the local file system.
+	Sources fs.FS
+
+	// changed are the last commit times of the files, see ChangeTimes.
+	changed           map[string]time.Time
+	changedGeneration int
+
+	// linted are the directories of the packages LintAllocations checked.
+	linted map[string]bool
+
+	// checked are the type-checked packages, see CheckedPackages.
+	checked *typeChecked
+	// escapeTypes are the escapes by type, see EscapesByType.
+	escapeTypes           []*EscapeType
+	escapeTypesGeneration int
+
+	// mapped are the logs mapped by MapInput.
+	mapped [][]byte
 }
 
 type File struct {
+	Key     string // key in Index.Files
 	Path    string
 	AbsPath string
 	Stats   Stats
 	Notes   []Note
+
+	// Generated is set when the file has a "Code generated" comment.
+	Generated bool
+	// Source is the content of synthetic files, which don't exist on disk.
+	Source []byte
+
+	seen map[noteKey]int
+}
+
+type noteKey struct {
+	Tool    string
+	Line    int
+	Column  int
+	Message string
 }
 
 type Note struct {
 	Line    int // 0 is the first line
 	Column  int // 0 is the first column
 	Message []byte
+	Count   int    // number of times the note appeared in the log
+	Tool    string // tool that produced the note, e.g. "compiler" or "vet"
+
+	Severity Severity
+	// Span is the range the tool reported, nil when it reported only a column.
+	Span *Span
+	// Category is set by tools that report it, e.g. "-Wunused-variable".
+	Category string
+	// Order is the position of the note in the logs among the notes of
+	// its file, 0 is the first.
+	Order int
 }
 
 func NewIndex() *Index {
 	index := &Index{}
+	index.Limits = DefaultLimits
 	index.Files = make(map[string]*File)
+	index.canonical = make(map[string]string)
 	return index
 }
 
+// caseInsensitive is set on platforms where the default file system
+// ignores case in paths.
+var caseInsensitive = runtime.GOOS == "windows" || runtime.GOOS == "darwin"
+
+// CanonicalPath returns the key under which path is stored in the index.
+// Symlinks are resolved and on case-insensitive platforms the path is
+// lowercased, so that each real file ends up with a single entry.
+func (index *Index) CanonicalPath(dir string, path string) string {
+	abspath := path
+	if !filepath.IsAbs(abspath) {
+		abspath = filepath.Join(dir, path)
+	}
+	index.canonicalMu.Lock()
+	canonical, ok := index.canonical[abspath]
+	index.canonicalMu.Unlock()
+	if ok {
+		return canonical
+	}
+
+	canonical = filepath.Clean(abspath)
+	if resolved, err := filepath.EvalSymlinks(canonical); err == nil {
+		canonical = resolved
+	}
+	if caseInsensitive {
+		canonical = strings.ToLower(canonical)
+	}
+
+	index.canonicalMu.Lock()
+	index.canonical[abspath] = canonical
+	index.canonicalMu.Unlock()
+	return canonical
+}
+
 func NewFile(dir string, path string) *File {
 	file := &File{}
-	file.Path = path
+	file.seen = make(map[noteKey]int)
 	if filepath.IsAbs(path) {
-		file.AbsPath = path
+		file.AbsPath = filepath.Clean(path)
 	} else {
 		file.AbsPath = filepath.Join(dir, path)
 	}
+	file.Path = DisplayPath(dir, file.AbsPath)
 	return file
 }
 
-func (index *Index) Parse(dir string, data []byte) {
+// DisplayPath returns abspath relative to dir when it is inside dir,
+// so that "./pkg/foo.go" and "/dir/pkg/foo.go" are shown the same way.
+func DisplayPath(dir string, abspath string) string {
+	rel, err := filepath.Rel(dir, abspath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return abspath
+	}
+	return rel
+}
+
+// Lookup finds a file by its index key, display path or absolute path.
+func (index *Index) Lookup(path string) (*File, bool) {
+	if file, ok := index.Files[path]; ok {
+		return file, true
+	}
+	if filepath.IsAbs(path) {
+		if file, ok := index.Files[index.CanonicalPath("", path)]; ok {
+			return file, true
+		}
+	}
+	for _, file := range index.Files {
+		if file.Path == path || file.AbsPath == path {
+			return file, true
+		}
+	}
+	return nil, false
+}
+
+// Parse adds all notes from data to the index,
+// plain diagnostics are labeled with tool.
+func (index *Index) Parse(dir string, tool string, data []byte) {
+	if tool == "" {
+		tool = "compiler"
+	}
+
+	if index.GoVersion == "" {
+		index.GoVersion = DetectGoVersion(data)
+	}
+	index.Logs = append(index.Logs, Log{tool, dir, data})
+
+	if parser := DetectInputParser(data); parser != nil {
+		if err := parser.ParseInput(index, dir, data); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	} else {
+		index.ParseLines(dir, tool, SplitLines(data))
+	}
+
+	index.FindLineDirectives()
+	index.Sort()
+	index.Generation++
+	index.Modified = time.Now()
+}
+
+// ParseLines adds notes from lines to the index, using the registered
+// parsers for the formats they detect.
+func (index *Index) ParseLines(dir string, tool string, lines [][]byte) {
+	for i := 0; i < len(lines); i++ {
+		if parser := DetectParser(lines[i]); parser != nil {
+			i = parser.Parse(index, dir, lines, i)
+			continue
+		}
+		index.Add(dir, tool, lines[i])
+	}
+}
+
+// SplitLines splits data into lines without the line endings.
+func SplitLines(data []byte) [][]byte {
+	var lines [][]byte
 	lineStart := 0
 	lineEnd := 0
 	for lineStart < len(data) {
@@ -50,25 +266,39 @@ func (index *Index) Parse(dir string, data []byte) {
 		if lineEnd < 0 {
 			lineEnd = len(data)
 		}
-		index.Add(dir, data[lineStart:lineEnd])
+		lines = append(lines, bytes.TrimSuffix(data[lineStart:lineEnd], []byte{'\r'}))
 		lineStart = lineEnd + 1
 	}
-
-	index.Sort()
+	return lines
 }
 
 func (index *Index) Sort() {
 	for _, file := range index.Files {
-		sort.Slice(file.Notes, func(i, k int) bool {
-			if file.Notes[i].Line == file.Notes[k].Line {
-				return file.Notes[i].Column < file.Notes[k].Column
-			}
-			return file.Notes[i].Line < file.Notes[k].Line
+		sort.SliceStable(file.Notes, func(i, k int) bool {
+			return noteLess(&file.Notes[i], &file.Notes[k])
 		})
+		for i, note := range file.Notes {
+			file.seen[noteKey{note.Tool, note.Line, note.Column, string(note.Message)}] = i
+		}
+	}
+}
+
+// noteLess is the order of the notes of a file: by line, column, tool and
+// then the position in the logs.
+func noteLess(a, b *Note) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	if a.Column != b.Column {
+		return a.Column < b.Column
+	}
+	if a.Tool != b.Tool {
+		return a.Tool < b.Tool
 	}
+	return a.Order < b.Order
 }
 
-func (index *Index) Add(dir string, line []byte) {
+func (index *Index) Add(dir string, tool string, line []byte) {
 	if len(line) <= 2 {
 		return
 	}
@@ -87,24 +317,176 @@ func (index *Index) Add(dir string, line []byte) {
 
 	pathbytes, lineno, col, msg, ok := ParseFileLine(line)
 	if !ok {
+		index.addUnparsed(line)
 		return
 	}
+	if string(pathbytes) == Autogenerated {
+		index.AddAutogenerated(tool, msg)
+		return
+	}
+
+	// diagnostics about the whole file are shown on its first line
+	if lineno < 1 {
+		lineno = 1
+	}
+	_, file := index.File(dir, string(pathbytes))
+	if file.AddNote(tool, lineno-1, col-1, msg) {
+		file.Stats.Add(msg)
+	}
+}
+
+// ExcludeGenerated removes generated files from the index, so that they
+// don't count towards statistics and reports.
+func (index *Index) ExcludeGenerated() {
+	for key, file := range index.Files {
+		if file.Generated {
+			delete(index.Files, key)
+		}
+	}
+}
 
-	path := string(pathbytes)
-	if runtime.GOOS == "windows" {
-		path = strings.ToLower(path)
+// Tools returns the sorted list of tools that produced notes.
+func (index *Index) Tools() []string {
+	seen := make(map[string]bool)
+	tools := []string{}
+	for _, file := range index.Files {
+		for _, note := range file.Notes {
+			if !seen[note.Tool] {
+				seen[note.Tool] = true
+				tools = append(tools, note.Tool)
+			}
+		}
 	}
+	sort.Strings(tools)
+	return tools
+}
 
-	file, ok := index.Files[path]
+// Dir is a directory containing annotated files.
+type Dir struct {
+	AbsPath string
+	Path    string
+}
+
+// Dirs returns the directories containing annotated files, sorted by path.
+func (index *Index) Dirs() []Dir {
+	seen := make(map[string]bool)
+	dirs := []Dir{}
+	for _, file := range index.Files {
+		if file.Source != nil {
+			continue
+		}
+		dir := filepath.Dir(file.AbsPath)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, Dir{
+				AbsPath: dir,
+				Path:    filepath.Dir(file.Path),
+			})
+		}
+	}
+	sort.Slice(dirs, func(i, k int) bool {
+		return dirs[i].AbsPath < dirs[k].AbsPath
+	})
+	return dirs
+}
+
+// FilesIn returns the files directly inside dir, sorted by path.
+func (index *Index) FilesIn(dir string) []*File {
+	dir = filepath.Clean(dir)
+	files := []*File{}
+	for _, file := range index.Files {
+		if filepath.Dir(file.AbsPath) == dir {
+			files = append(files, file)
+		}
+	}
+	sort.Slice(files, func(i, k int) bool {
+		return files[i].AbsPath < files[k].AbsPath
+	})
+	return files
+}
+
+// SortedFiles returns all files sorted by path.
+func (index *Index) SortedFiles() []*File {
+	files := make([]*File, 0, len(index.Files))
+	for _, file := range index.Files {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, k int) bool {
+		return files[i].Path < files[k].Path
+	})
+	return files
+}
+
+// File returns the file for path, adding it to the index when missing.
+func (index *Index) File(dir string, path string) (key string, file *File) {
+	dir, path, key = index.locate(dir, path)
+	file, ok := index.Files[key]
 	if !ok {
 		file = NewFile(dir, path)
-		index.Files[path] = file
+		file.Path = index.rootPath(dir, file)
+		if data, err := index.ReadSource(file.AbsPath); err == nil {
+			file.Generated = IsGenerated(data)
+		}
+		file.Key = key
+		index.Files[key] = file
 	}
+	return key, file
+}
+
+// locate translates path of a log parsed in dir to the file it refers to,
+// returns the root the path is relative to and the index key.
+func (index *Index) locate(dir string, path string) (root string, translated string, key string) {
+	if index.bazelWorkspace == nil {
+		workspace := FindBazelWorkspace(dir)
+		index.bazelWorkspace = &workspace
+	}
+	if *index.bazelWorkspace != "" {
+		path = BazelPath(*index.bazelWorkspace, path)
+	}
+
+	path = index.stdlibPath(dir, path)
+
+	root = index.root(dir, path)
+	return root, path, index.CanonicalPath(root, path)
+}
 
-	file.Stats.Add(msg)
+// AddNote adds a note to the file, returns false when an identical
+// note was already present and only its count was incremented.
+func (file *File) AddNote(tool string, line, column int, msg []byte) bool {
+	key := noteKey{tool, line, column, string(msg)}
+	if i, ok := file.seen[key]; ok {
+		file.Notes[i].Count++
+		return false
+	}
+
+	file.seen[key] = len(file.Notes)
 	file.Notes = append(file.Notes, Note{
-		Line:    lineno - 1,
-		Column:  col - 1,
+		Line:    line,
+		Column:  column,
 		Message: msg,
+		Count:   1,
+		Tool:    tool,
+		Order:   len(file.Notes),
+
+		Severity: Classify(tool, msg),
 	})
+	return true
+}
+
+// AddStructuredNote adds a note from a tool that reports the severity and
+// category of its diagnostics, returns false when an identical note was
+// already present and only its count was incremented.
+func (file *File) AddStructuredNote(note Note) bool {
+	key := noteKey{note.Tool, note.Line, note.Column, string(note.Message)}
+	if i, ok := file.seen[key]; ok {
+		file.Notes[i].Count++
+		return false
+	}
+
+	note.Count = 1
+	note.Order = len(file.Notes)
+	file.seen[key] = len(file.Notes)
+	file.Notes = append(file.Notes, note)
+	file.Stats.Add(note.Message)
+	return true
 }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		data  string
+		lines []string
+	}{
+		{"", nil},
+		{"\n", []string{""}},
+		{"a\nb", []string{"a", "b"}},
+		{"a\nb\n", []string{"a", "b"}},
+		{"a\r\nb\r\n", []string{"a", "b"}},
+		{"a\r\nb\nc\r\nd", []string{"a", "b", "c", "d"}},
+		{"a\r\n\r\n\nb", []string{"a", "", "", "b"}},
+		{"a\r", []string{"a"}},
+		// like the compiler, a lone \r doesn't end a line
+		{"a\rb\r\nc\n", []string{"a\rb", "c"}},
+	}
+	for _, test := range tests {
+		var lines []string
+		for _, line := range SplitLines([]byte(test.data)) {
+			lines = append(lines, string(line))
+		}
+		if !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("SplitLines(%q) = %q, want %q", test.data, lines, test.lines)
+		}
+	}
+}
+
+func TestParseMixedLineEndings(t *testing.T) {
+	index := NewIndex()
+	index.Sources = fstest.MapFS{}
+	index.Parse("/src", "", []byte("a.go:1:2: can inline f\r\na.go:2:3: x escapes to heap\na.go:3:4: moved to heap: y\r\n"))
+
+	file, ok := index.Lookup("a.go")
+	if !ok {
+		t.Fatal("a.go not indexed")
+	}
+	want := []string{"can inline f", "x escapes to heap", "moved to heap: y"}
+	if len(file.Notes) != len(want) {
+		t.Fatalf("got %v notes, want %v", len(file.Notes), len(want))
+	}
+	for i, note := range file.Notes {
+		if string(note.Message) != want[i] || note.Line != i || note.Column != i+1 {
+			t.Errorf("note %v is %v:%v %q, want %v:%v %q", i, note.Line, note.Column, note.Message, i, i+1, want[i])
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InlineCandidate is a function the inliner rejected for its cost.
+type InlineCandidate struct {
+	Path   string
+	Line   int // 1 is the first line
+	Func   string
+	Cost   int
+	Budget int
+}
+
+var tooComplex = []byte(": function too complex: cost ")
+
+// InlineCandidates returns the functions that would be inlined if the
+// inlining budget were raised to budget, cheapest first. The costs come from
+// "cannot inline" notes of go build -gcflags=-m=2:
+//
+//	cannot inline Foo: function too complex: cost 95 exceeds budget 80
+func (index *Index) InlineCandidates(budget int) []InlineCandidate {
+	candidates := []InlineCandidate{}
+	for _, file := range index.Files {
+		for _, note := range file.Notes {
+			msg := note.Message
+			if !bytes.HasPrefix(msg, []byte("cannot inline ")) {
+				continue
+			}
+			p := bytes.Index(msg, tooComplex)
+			if p < 0 {
+				continue
+			}
+
+			// "95 exceeds budget 80"
+			fields := strings.Fields(string(msg[p+len(tooComplex):]))
+			if len(fields) < 4 {
+				continue
+			}
+			cost, err1 := strconv.Atoi(fields[0])
+			limit, err2 := strconv.Atoi(fields[3])
+			if err1 != nil || err2 != nil || cost > budget {
+				continue
+			}
+
+			candidates = append(candidates, InlineCandidate{
+				Path:   file.Path,
+				Line:   note.Line + 1,
+				Func:   string(msg[len("cannot inline "):p]),
+				Cost:   cost,
+				Budget: limit,
+			})
+		}
+	}
+	sort.Slice(candidates, func(i, k int) bool {
+		a, b := &candidates[i], &candidates[k]
+		if a.Cost != b.Cost {
+			return a.Cost < b.Cost
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+	return candidates
+}
+
+// InlineBudget implements the inline-budget subcommand: it lists the
+// functions that would inline with a larger budget and, when -gcflags is
+// given, rebuilds the packages with those flags and prints the inlining
+// decisions that changed.
+func InlineBudget(args []string, w io.Writer) error {
+	set := flag.NewFlagSet("inline-budget", flag.ExitOnError)
+	budget := set.Int("budget", 160, "inlining budget to simulate")
+	logName := set.String("log", "", "existing -m=2 compiler log to use instead of building the packages")
+	gcflags := set.String("gcflags", "", "additional compiler flags for a second build, e.g. \"-l=4\", whose inlining decisions are compared")
+	set.Parse(args)
+
+	packages := set.Args()
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+	if *logName != "" && *gcflags != "" {
+		return errors.New("inline-budget: -log and -gcflags cannot be combined")
+	}
+
+	dir, _ := filepath.Abs(".")
+	var data []byte
+	var err error
+	if *logName != "" {
+		data, err = ReadInput(*logName)
+	} else {
+		data, err = compilerOutput(packages, "-m=2")
+	}
+	if err != nil {
+		return err
+	}
+	before := NewIndex()
+	before.Parse(dir, "", data)
+
+	candidates := before.InlineCandidates(*budget)
+	if len(candidates) == 0 {
+		fmt.Fprintf(w, "No functions would inline with budget %v.\n", *budget)
+	}
+	for _, c := range candidates {
+		fmt.Fprintf(w, "%s:%d: %s would inline: cost %d, budget %d\n", c.Path, c.Line, c.Func, c.Cost, c.Budget)
+	}
+
+	if *gcflags == "" {
+		return nil
+	}
+
+	data, err = compilerOutput(packages, "-m=2 "+*gcflags)
+	if err != nil {
+		return err
+	}
+	after := NewIndex()
+	after.Parse(dir, "", data)
+
+	fmt.Fprintf(w, "\nInlining changes with %s:\n", *gcflags)
+	found := false
+	for _, change := range DiffIndexes(before, after) {
+		if !strings.Contains(change.Message, "inlin") {
+			continue
+		}
+		found = true
+		sign := "-"
+		if change.Added {
+			sign = "+"
+		}
+		fmt.Fprintf(w, "\t%s %s:%d: %s\n", sign, change.Path, change.Line, change.Message)
+	}
+	if !found {
+		fmt.Fprintf(w, "\tno inlining changes\n")
+	}
+	return nil
+}
+
+// compilerOutput builds packages with gcflags and returns the compiler
+// diagnostics. Build failures are reported together with the output.
+func compilerOutput(packages []string, gcflags string) ([]byte, error) {
+	args := append([]string{"build", "-o", os.DevNull, "-gcflags=" + gcflags}, packages...)
+	cmd := exec.Command("go", args...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go %s: %v\n%s", strings.Join(args, " "), err, output.Bytes())
+	}
+	return output.Bytes(), nil
+}
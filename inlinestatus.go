@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InlineStatus is the inlining decision of the compiler about an exported function.
+type InlineStatus struct {
+	Key     string `json:"key"`
+	Path    string `json:"path"`
+	Line    int    `json:"line"` // 1 is the first line
+	Package string `json:"package"`
+	Dir     string `json:"dir"`
+	Func    string `json:"func"`
+
+	// Status is "inlinable", "not inlinable" or "unknown" when the
+	// compiler did not report about the function.
+	Status string `json:"status"`
+	// Reason is why the function cannot be inlined.
+	Reason string `json:"reason,omitempty"`
+	// Cost is reported by -m=2, 0 when unknown.
+	Cost int `json:"cost,omitempty"`
+}
+
+// InlineStatuses lists the exported functions and methods of the annotated
+// Go files with the inlining decision about them, sorted by package and
+// name. Only functions of packages with the name or directory pkg are
+// listed, unless pkg is empty.
+func (index *Index) InlineStatuses(pkg string) []InlineStatus {
+	statuses := []InlineStatus{}
+	for key, file := range index.Files {
+		if file.Source != nil || filepath.Ext(file.AbsPath) != ".go" || strings.HasSuffix(file.AbsPath, "_test.go") {
+			continue
+		}
+		name, funcs := index.goFuncs(file.AbsPath)
+		dir := filepath.Dir(file.Path)
+		if pkg != "" && pkg != name && pkg != dir {
+			continue
+		}
+
+		for _, fn := range funcs {
+			if !isExportedFunc(fn.Name) {
+				continue
+			}
+			status := InlineStatus{
+				Key:     key,
+				Path:    file.Path,
+				Line:    fn.From,
+				Package: name,
+				Dir:     dir,
+				Func:    fn.Name,
+				Status:  "unknown",
+			}
+			for _, note := range file.Notes {
+				if note.Line+1 == fn.From {
+					status.parse(fn.Name, note.Message)
+				}
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	sort.Slice(statuses, func(i, k int) bool {
+		a, b := &statuses[i], &statuses[k]
+		if a.Dir != b.Dir {
+			return a.Dir < b.Dir
+		}
+		return a.Func < b.Func
+	})
+	return statuses
+}
+
+// parse updates status from a note at the declaration of function name,
+// notes about closures declared on the same line are ignored:
+//
+//	can inline Foo
+//	can inline Foo with cost 12 as: func() int { return 1 }
+//	cannot inline Foo: function too complex: cost 95 exceeds budget 80
+//	cannot inline Foo: recursive
+func (status *InlineStatus) parse(name string, msg []byte) {
+	can := []byte("can inline " + name)
+	cannot := []byte("cannot inline " + name + ": ")
+	switch {
+	case bytes.Equal(msg, can) || bytes.HasPrefix(msg, append(can, ' ')):
+		status.Status = "inlinable"
+		if p := bytes.Index(msg, []byte(" with cost ")); p >= 0 {
+			status.Cost = leadingInt(msg[p+len(" with cost "):])
+		}
+	case bytes.HasPrefix(msg, cannot):
+		status.Status = "not inlinable"
+		status.Reason = string(msg[len(cannot):])
+		if p := bytes.Index(msg, tooComplex); p >= 0 {
+			status.Cost = leadingInt(msg[p+len(tooComplex):])
+		}
+	}
+}
+
+// leadingInt parses the number at the start of data, 0 when there is none.
+func leadingInt(data []byte) int {
+	end := 0
+	for end < len(data) && '0' <= data[end] && data[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(string(data[:end]))
+	return n
+}
+
+// isExportedFunc reports whether a function named like "Foo" or "(*T).Foo"
+// is part of the package API, methods need an exported receiver type.
+func isExportedFunc(name string) bool {
+	if !strings.HasPrefix(name, "(") {
+		return token.IsExported(name)
+	}
+	end := strings.Index(name, ").")
+	if end < 0 {
+		return false
+	}
+	receiver := strings.TrimPrefix(name[1:end], "*")
+	if p := strings.IndexByte(receiver, '['); p >= 0 {
+		receiver = receiver[:p]
+	}
+	return token.IsExported(receiver) && token.IsExported(name[end+2:])
+}
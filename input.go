@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// SplitInput splits an input argument of the form "tool=name" into the tool
+// label and the input name, e.g. "vet=vet.log". Plain names have no label.
+func SplitInput(input string) (tool, name string) {
+	for i, r := range input {
+		switch {
+		case r == '=' && i > 0:
+			return input[:i], input[i+1:]
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '-', r == '_':
+		default:
+			return "", input
+		}
+	}
+	return "", input
+}
+
+// ReadInput reads the whole log from name, or stdin when name is empty.
+// A directory is read as a compiler optimization log.
+func ReadInput(name string) ([]byte, error) {
+	var rd io.Reader = os.Stdin
+	if name != "" {
+		if info, err := os.Stat(name); err == nil && info.IsDir() {
+			return ReadCompilerLogs(name)
+		}
+		file, err := OpenInput(name)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		rd = file
+	}
+
+	rd, err := Decompress(rd)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(rd)
+}
+
+// OpenInput opens a log from a local file or, when name is an URL, downloads it.
+func OpenInput(name string) (io.ReadCloser, error) {
+	if IsURL(name) {
+		return OpenURL(name)
+	}
+	return os.Open(name)
+}
+
+// IsURL reports whether the input name is an URL.
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// OpenURL fetches the log from url, adding the -header flag to the request.
+func OpenURL(url string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if *header != "" {
+		colon := strings.IndexByte(*header, ':')
+		if colon < 0 {
+			return nil, fmt.Errorf("invalid header %q", *header)
+		}
+		req.Header.Set(strings.TrimSpace((*header)[:colon]), strings.TrimSpace((*header)[colon+1:]))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %v: %v", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Decompress detects gzip or zstd compressed input and returns
+// a reader for the decompressed content.
+func Decompress(rd io.Reader) (io.Reader, error) {
+	buf := bufio.NewReader(rd)
+	magic, _ := buf.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(buf)
+	case bytes.HasPrefix(magic, zstdMagic):
+		// there's no zstd in the standard library, use the zstd tool
+		cmd := exec.Command("zstd", "-dc")
+		cmd.Stdin = buf
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("zstd: %v", err)
+		}
+		return bytes.NewReader(out), nil
+	}
+	return buf, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import "unicode/utf8"
+
+// Limits protect the server and the browser from pathological files,
+// e.g. huge generated tables. Zero values disable a limit.
+type Limits struct {
+	FileSize   int64 // bytes of a source file that are read
+	LineLength int   // bytes of a line that are shown
+	LineNotes  int   // notes of a line that are shown
+}
+
+// DefaultLimits are the limits of the -max-* flags.
+var DefaultLimits = Limits{
+	FileSize:   16 << 20,
+	LineLength: 10000,
+	LineNotes:  100,
+}
+
+// truncateFile cuts data after the last complete line within the file size
+// limit and reports whether it did.
+func (limits Limits) truncateFile(data []byte) ([]byte, bool) {
+	if limits.FileSize <= 0 || int64(len(data)) <= limits.FileSize {
+		return data, false
+	}
+	data = data[:limits.FileSize]
+	for i := len(data) - 1; i >= 0; i-- {
+		if data[i] == '\n' {
+			return data[:i+1], true
+		}
+	}
+	return data, true
+}
+
+// truncateLine cuts line to the line length limit at a rune boundary and
+// returns the number of bytes cut.
+func (limits Limits) truncateLine(line string) (string, int) {
+	if limits.LineLength <= 0 || len(line) <= limits.LineLength {
+		return line, 0
+	}
+	end := limits.LineLength
+	for end > 0 && !utf8.RuneStart(line[end]) {
+		end--
+	}
+	return line[:end], len(line) - end
+}
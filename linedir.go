@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LineDirective is a //line directive in a generated Go file:
+//
+//	//line parser.y:42
+//
+// Lines following the directive are reported by the compiler at positions
+// in File starting from OrigLine.
+type LineDirective struct {
+	Line     int // 0-based line following the directive
+	File     string
+	OrigLine int // 1 is the first line
+}
+
+// ParseLineDirectives returns the //line directives in data, relative file
+// names are resolved against dir, the directory of the generated file.
+func ParseLineDirectives(dir string, data []byte) []LineDirective {
+	var directives []LineDirective
+	for i, line := range SplitLines(data) {
+		if !bytes.HasPrefix(line, []byte("//line ")) {
+			continue
+		}
+		pos := string(bytes.TrimSpace(line[len("//line "):]))
+
+		// both "file:line" and "file:line:col" are valid
+		file, lineno, ok := splitPosition(pos)
+		if file2, lineno2, ok2 := splitPosition(file); ok && ok2 {
+			file, lineno = file2, lineno2
+		}
+		if !ok || file == "" {
+			continue
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		directives = append(directives, LineDirective{
+			Line:     i + 1,
+			File:     file,
+			OrigLine: lineno,
+		})
+	}
+	return directives
+}
+
+// splitPosition splits "file:123" into the file and the number.
+func splitPosition(pos string) (string, int, bool) {
+	colon := strings.LastIndexByte(pos, ':')
+	if colon < 0 {
+		return pos, 0, false
+	}
+	n, err := strconv.Atoi(pos[colon+1:])
+	if err != nil || n <= 0 {
+		return pos, 0, false
+	}
+	return pos[:colon], n, true
+}
+
+// FindLineDirectives looks for generated Go files with //line directives in
+// the directories of annotated files. The compiler reports positions in the
+// original sources for them, so they are added to the index to allow viewing
+// the annotations at the generated positions.
+func (index *Index) FindLineDirectives() {
+	if index.lineDirectives == nil {
+		index.lineDirectives = make(map[string][]LineDirective)
+	}
+	for _, dir := range index.Dirs() {
+		names, _ := fs.Glob(index.sources(), path.Join(SourceName(dir.AbsPath), "*.go"))
+		for _, name := range names {
+			path := filepath.Join(dir.AbsPath, filepath.Base(name))
+			key := index.CanonicalPath("", path)
+			if _, ok := index.lineDirectives[key]; ok {
+				continue
+			}
+			data, err := index.ReadSource(path)
+			if err != nil {
+				continue
+			}
+			directives := ParseLineDirectives(dir.AbsPath, data)
+			index.lineDirectives[key] = directives
+			if len(directives) > 0 {
+				index.File(dir.AbsPath, path)
+			}
+		}
+	}
+}
+
+// GeneratedNotes returns the notes of file together with the notes of the
+// original sources mapped to the lines of file through its //line directives.
+func (index *Index) GeneratedNotes(file *File) []Note {
+	directives := index.lineDirectives[file.Key]
+	if len(directives) == 0 {
+		return file.Notes
+	}
+
+	// notes after the first directive are at positions of the original
+	// sources, which may be file itself
+	notes := []Note{}
+	for _, note := range file.Notes {
+		if note.Line < directives[0].Line {
+			notes = append(notes, note)
+		}
+	}
+	for i, directive := range directives {
+		original, ok := index.Files[index.CanonicalPath("", directive.File)]
+		if !ok {
+			continue
+		}
+
+		// the directive applies until the next one
+		count := -1
+		if i+1 < len(directives) {
+			count = directives[i+1].Line - 1 - directive.Line
+		}
+		first := directive.OrigLine - 1
+		for _, note := range original.Notes {
+			if note.Line < first || (count >= 0 && note.Line >= first+count) {
+				continue
+			}
+			note.Line = directive.Line + note.Line - first
+			notes = append(notes, note)
+		}
+	}
+
+	sort.SliceStable(notes, func(i, k int) bool {
+		return noteLess(&notes[i], &notes[k])
+	})
+	return notes
+}
@@ -12,38 +12,52 @@ import (
 // ..\..\abc.go:688: cannot inline ...
 // C:\Go\src\example\abc.go:688: cannot inline ...
 // C:\Go\src\example\abc.go:688:123: cannot inline ...
+// /home/me/My Projects/pkg (copy)/abc.go:688:123: cannot inline ...
+// /tmp/a:b/abc.go:688: cannot inline ...
+//
+// The path ends at the first ":line:" or ":line:col:" followed by a space,
+// so paths may contain spaces, parentheses and colons.
 func ParseFileLine(line []byte) (path []byte, lineno, column int, msg []byte, ok bool) {
 	lineno = -1
 	column = -1
-
-	// skip first 2 characters, to handle windows letter "C:"
-	first := IndexByteAt(line, 2, ':')
-	if first < 0 {
-		return
-	}
-	second := IndexByteAt(line, first+1, ':')
-	if second < 0 {
-		return
-	}
-	third := IndexByteAt(line, second+1, ' ')
-	if third < 0 {
+	if len(line) < 2 {
 		return
 	}
 
-	path = line[:first]
-	lineno, ok = ParseInt(line[first+1 : second])
-	if !ok {
-		return
-	}
-	if second+1 < third-1 {
-		if col, colok := ParseInt(line[second+1 : third-1]); colok {
-			column = col
+	for first := IndexByteAt(line, 1, ':'); first >= 0; first = IndexByteAt(line, first+1, ':') {
+		second, n := parseDigitsColon(line, first+1)
+		if second < 0 {
+			continue
 		}
+		end, col := parseDigitsColon(line, second+1)
+		if end < 0 {
+			end, col = second, -1
+		}
+		if end+1 >= len(line) || line[end+1] != ' ' {
+			continue
+		}
+		return line[:first], n, col, line[end+2:], true
 	}
-	msg = line[third+1:]
 	return
 }
 
+// parseDigitsColon parses a number followed by ':' at data[at:],
+// returns the index of the colon or -1.
+func parseDigitsColon(data []byte, at int) (colon int, n int) {
+	end := at
+	for end < len(data) && '0' <= data[end] && data[end] <= '9' {
+		end++
+	}
+	if end == at || end >= len(data) || data[end] != ':' {
+		return -1, 0
+	}
+	n, ok := ParseInt(data[at:end])
+	if !ok {
+		return -1, 0
+	}
+	return end, n
+}
+
 func ParseInt(data []byte) (int, bool) {
 	x, err := strconv.Atoi(string(data))
 	if err != nil {
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestParseFileLine(t *testing.T) {
+	tests := []struct {
+		line   string
+		path   string
+		lineno int
+		column int
+		msg    string
+		ok     bool
+	}{
+		{"../../abc.go:688: cannot inline f", "../../abc.go", 688, -1, "cannot inline f", true},
+		{"/go/src/abc.go:688: cannot inline f", "/go/src/abc.go", 688, -1, "cannot inline f", true},
+		{"/go/src/abc.go:688:123: cannot inline f", "/go/src/abc.go", 688, 123, "cannot inline f", true},
+		{`..\..\abc.go:688: cannot inline f`, `..\..\abc.go`, 688, -1, "cannot inline f", true},
+		{`C:\Go\src\example\abc.go:688: cannot inline f`, `C:\Go\src\example\abc.go`, 688, -1, "cannot inline f", true},
+		{`C:\Go\src\example\abc.go:688:123: cannot inline f`, `C:\Go\src\example\abc.go`, 688, 123, "cannot inline f", true},
+		{`C:\My Projects (x86)\abc.go:7:6: can inline f`, `C:\My Projects (x86)\abc.go`, 7, 6, "can inline f", true},
+		{"/home/me/My Projects/pkg (copy)/abc.go:688:123: cannot inline f", "/home/me/My Projects/pkg (copy)/abc.go", 688, 123, "cannot inline f", true},
+		{"/tmp/a:b/abc.go:688: cannot inline f", "/tmp/a:b/abc.go", 688, -1, "cannot inline f", true},
+		{"/tmp/a:1/abc.go:688:5: x escapes to heap", "/tmp/a:1/abc.go", 688, 5, "x escapes to heap", true},
+		{"abc.go:10:2: message: with: colons", "abc.go", 10, 2, "message: with: colons", true},
+		{"abc.go:688: ", "abc.go", 688, -1, "", true},
+
+		{"", "", -1, -1, "", false},
+		{"a", "", -1, -1, "", false},
+		{"# example.com/pkg", "", -1, -1, "", false},
+		{"abc.go:688:", "", -1, -1, "", false},
+		{"abc.go:688:123:", "", -1, -1, "", false},
+		{"abc.go:x: message", "", -1, -1, "", false},
+	}
+	for _, test := range tests {
+		path, lineno, column, msg, ok := ParseFileLine([]byte(test.line))
+		if string(path) != test.path || lineno != test.lineno || column != test.column || string(msg) != test.msg || ok != test.ok {
+			t.Errorf("ParseFileLine(%q) = %q, %v, %v, %q, %v; want %q, %v, %v, %q, %v", test.line,
+				path, lineno, column, msg, ok,
+				test.path, test.lineno, test.column, test.msg, test.ok)
+		}
+	}
+}
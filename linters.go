@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterParser(70, errcheckParser{})
+	RegisterParser(80, reviveParser{})
+	RegisterParser(130, gosecParser{})
+	RegisterParser(140, semgrepParser{})
+}
+
+// Most standalone linters, e.g. ineffassign, staticcheck and revive with its
+// default formatter, print plain "path:line:col: message" diagnostics.
+// The parsers here adapt the formats that deviate from it.
+
+// errcheckParser handles errcheck output, which has the unchecked call
+// after a tab instead of a message:
+//
+//	pkg/foo.go:12:9:	f.Close()
+type errcheckParser struct{}
+
+func (errcheckParser) Detect(line []byte) bool {
+	_, _, _, _, ok := parseErrcheck(line)
+	return ok
+}
+
+func (errcheckParser) Parse(index *Index, dir string, lines [][]byte, at int) int {
+	path, lineno, column, msg, _ := parseErrcheck(lines[at])
+	_, file := index.File(dir, string(path))
+	if file.AddNote("errcheck", lineno-1, column-1, msg) {
+		file.Stats.Add(msg)
+	}
+	return at
+}
+
+// parseErrcheck parses an errcheck diagnostic, msg describes the unchecked call.
+func parseErrcheck(line []byte) (path []byte, lineno, column int, msg []byte, ok bool) {
+	tab := bytes.Index(line, []byte(":\t"))
+	if tab < 0 {
+		return nil, 0, 0, nil, false
+	}
+	call := bytes.TrimSpace(line[tab+2:])
+	if len(call) == 0 {
+		return nil, 0, 0, nil, false
+	}
+	diagnostic := append(append([]byte{}, line[:tab]...), ": error return value not checked: "...)
+	diagnostic = append(diagnostic, call...)
+	path, lineno, column, msg, ok = ParseFileLine(diagnostic)
+	if !ok || len(path) >= tab || column < 0 {
+		return nil, 0, 0, nil, false
+	}
+	return path, lineno, column, msg, true
+}
+
+// reviveParser handles the friendly formatter of revive, which prints the
+// position on the line after the rule and message:
+//
+//	⚠  https://revive.run/r#exported  exported function Foo should have comment or be unexported
+//	pkg/foo.go:5:1
+//
+// Notes are labeled with the rule, e.g. "revive/exported".
+type reviveParser struct{}
+
+const reviveRuleURL = "https://revive.run/r#"
+
+func (reviveParser) Detect(line []byte) bool {
+	return bytes.Contains(line, []byte(reviveRuleURL))
+}
+
+func (reviveParser) Parse(index *Index, dir string, lines [][]byte, at int) int {
+	header := lines[at]
+	failure := bytes.HasPrefix(bytes.TrimSpace(header), []byte("✘"))
+
+	rest := header[bytes.Index(header, []byte(reviveRuleURL))+len(reviveRuleURL):]
+	rule, msg := rest, []byte{}
+	if space := bytes.IndexByte(rest, ' '); space >= 0 {
+		rule, msg = rest[:space], bytes.TrimSpace(rest[space:])
+	}
+
+	if at+1 >= len(lines) {
+		return at
+	}
+	position := bytes.TrimSpace(lines[at+1])
+	path, lineno, column, msg, ok := ParseFileLine(append(append(append([]byte{}, position...), ": "...), msg...))
+	if !ok {
+		return at
+	}
+
+	_, file := index.File(dir, string(path))
+	if file.AddNote("revive/"+string(rule), lineno-1, column-1, msg) {
+		if failure {
+			file.Notes[len(file.Notes)-1].Severity = Error
+		}
+		file.Stats.Add(msg)
+	}
+	return at + 1
+}
+
+// gosecParser handles the default text format of gosec, an issue with the
+// source around it, where the location is colored unless -nocolor is given:
+//
+//	[/src/pkg/foo.go:12] - G104 (CWE-703): Errors unhandled. (Confidence: HIGH, Severity: LOW)
+//	    11: func Close(f *os.File) {
+//	  > 12: 	f.Close()
+//	    13: }
+//
+// Notes are labeled "gosec" and categorized by the rule, the severity of
+// the issue is that of the note.
+type gosecParser struct{}
+
+var (
+	gosecIssue = regexp.MustCompile(`^\[(.+):(\d+)(?:-\d+)?(?::(\d+))?\] - (G\d+) \(CWE-(\d*)\): (.*) \(Confidence: (\w+), Severity: (\w+)\)$`)
+	gosecCode  = regexp.MustCompile(`^\s+>?\s*\d+:`)
+	ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+var gosecSeverity = map[string]Severity{
+	"HIGH":   Error,
+	"MEDIUM": Warning,
+	"LOW":    Info,
+}
+
+func (gosecParser) Detect(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("[")) && bytes.Contains(line, []byte("(Confidence: ")) &&
+		gosecIssue.Match(ansiEscape.ReplaceAll(line, nil))
+}
+
+func (gosecParser) Parse(index *Index, dir string, lines [][]byte, at int) int {
+	match := gosecIssue.FindSubmatch(ansiEscape.ReplaceAll(lines[at], nil))
+	lineno, _ := strconv.Atoi(string(match[2]))
+	column, _ := strconv.Atoi(string(match[3]))
+	msg := string(match[6])
+	if len(match[5]) > 0 {
+		msg += " (CWE-" + string(match[5]) + ")"
+	}
+
+	_, file := index.File(dir, string(match[1]))
+	file.AddStructuredNote(Note{
+		Line:     lineno - 1,
+		Column:   column - 1,
+		Message:  []byte(msg),
+		Tool:     "gosec",
+		Severity: gosecSeverity[string(match[8])],
+		Category: string(match[4]),
+	})
+
+	for at+1 < len(lines) && gosecCode.Match(lines[at+1]) {
+		at++
+	}
+	return at
+}
+
+// semgrepParser handles the default text format of semgrep, findings
+// grouped by file and rule, with the matched source:
+//
+//	 src/app.go
+//	❯❯❱ go.lang.security.audit.dangerous-exec-command
+//	       Detected non-static command inside Command.
+//	       Details: https://sg.run/...
+//
+//	        12┆ exec.Command(name)
+//	         ⋮┆----------------------------------------
+//	        20┆ exec.Command(other)
+//
+// Each source block is a finding of the rule. Notes are labeled "semgrep"
+// and categorized by the rule, the number of arrows is the severity.
+type semgrepParser struct{}
+
+var semgrepCode = regexp.MustCompile(`^\s*(\d+)┆`)
+
+func (semgrepParser) Detect(line []byte) bool {
+	_, _, ok := semgrepRule(line)
+	return ok
+}
+
+// semgrepRule parses the rule line of a finding.
+func semgrepRule(line []byte) (rule string, severity Severity, ok bool) {
+	trimmed := strings.TrimSpace(string(line))
+	marker := strings.IndexRune(trimmed, '❱')
+	if marker < 0 || strings.Trim(trimmed[:marker], "❯") != "" {
+		return "", Info, false
+	}
+	rule = strings.TrimSpace(trimmed[marker+len("❱"):])
+	if rule == "" || strings.ContainsAny(rule, " ┆") {
+		return "", Info, false
+	}
+	switch strings.Count(trimmed[:marker], "❯") {
+	case 0:
+		severity = Info
+	case 1:
+		severity = Warning
+	default:
+		severity = Error
+	}
+	return rule, severity, true
+}
+
+func (semgrepParser) Parse(index *Index, dir string, lines [][]byte, at int) int {
+	rule, severity, _ := semgrepRule(lines[at])
+
+	// the file is the last line indented by 4 spaces before the rule
+	path := ""
+	for i := at - 1; i >= 0 && path == ""; i-- {
+		line := string(lines[i])
+		if strings.HasPrefix(line, "    ") && !strings.HasPrefix(line, "     ") && !strings.Contains(line, "┆") {
+			if _, _, ok := semgrepRule(lines[i]); !ok {
+				path = strings.TrimSpace(line)
+			}
+		}
+	}
+
+	var message []string
+	var findings []int
+	inFinding := false
+	for at+1 < len(lines) {
+		line := string(lines[at+1])
+		trimmed := strings.TrimSpace(line)
+		if _, _, ok := semgrepRule(lines[at+1]); ok {
+			break
+		}
+		if trimmed != "" && !strings.HasPrefix(line, "     ") {
+			// the next file or the summary
+			break
+		}
+		at++
+		switch {
+		case strings.HasPrefix(trimmed, "⋮┆"):
+			inFinding = false
+		case semgrepCode.MatchString(line):
+			if !inFinding {
+				lineno, _ := strconv.Atoi(semgrepCode.FindStringSubmatch(line)[1])
+				findings = append(findings, lineno)
+				inFinding = true
+			}
+		case strings.Contains(trimmed, "┆"), strings.HasPrefix(trimmed, "Details: "):
+			// autofixes and the link to the rule
+		case trimmed != "" && len(findings) == 0:
+			message = append(message, trimmed)
+		}
+	}
+	if path == "" {
+		return at
+	}
+
+	_, file := index.File(dir, path)
+	for _, lineno := range findings {
+		file.AddStructuredNote(Note{
+			Line:     lineno - 1,
+			Column:   -1,
+			Message:  []byte(strings.Join(message, " ")),
+			Tool:     "semgrep",
+			Severity: severity,
+			Category: rule,
+		})
+	}
+	return at
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestErrcheckParser(t *testing.T) {
+	checkParser(t, errcheckParser{}, `
+pkg/foo.go:12:9:	f.Close()`, []string{
+		"pkg/foo.go:12:9: errcheck: error return value not checked: f.Close()",
+	})
+}
+
+func TestReviveParser(t *testing.T) {
+	checkParser(t, reviveParser{}, `
+⚠  https://revive.run/r#exported  exported function Foo should have comment or be unexported
+pkg/foo.go:5:1`, []string{
+		"pkg/foo.go:5:1: revive/exported: exported function Foo should have comment or be unexported",
+	})
+}
+
+func TestGosecParser(t *testing.T) {
+	checkParser(t, gosecParser{}, `
+[/src/pkg/foo.go:12] - G104 (CWE-703): Errors unhandled. (Confidence: HIGH, Severity: LOW)
+    11: func Close(f *os.File) {
+  > 12: 	f.Close()
+    13: }`, []string{
+		"pkg/foo.go:12:0: gosec: Errors unhandled. (CWE-703)",
+	})
+}
+
+func TestSemgrepParser(t *testing.T) {
+	checkParser(t, semgrepParser{}, `
+    src/app.go
+   ❯❯❱ go.lang.security.audit.dangerous-exec-command
+          Detected non-static command inside Command.
+
+           12┆ exec.Command(name)`, []string{
+		"src/app.go:12:0: semgrep: Detected non-static command inside Command.",
+	})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// maxLogLines limits the lines rendered on the log page, /raw/log has all.
+const maxLogLines = 100000
+
+// LogPage is the original log with the locations linked to the files.
+type LogPage struct {
+	Tool  string
+	Tools []string
+	Lines []LogLine
+	// Truncated is set when only the first maxLogLines lines are included.
+	Truncated bool
+}
+
+// LogLine is a line of a log, Location is the "path:line:col:" prefix of the
+// lines that refer to an indexed file.
+type LogLine struct {
+	Location string
+	Text     string
+	Key      string
+	Line     int
+}
+
+// LogLines returns the lines of the logs of tool, or of all logs, with the
+// locations of indexed files resolved.
+func (index *Index) LogLines(tool string, max int) ([]LogLine, bool) {
+	lines := []LogLine{}
+	for _, log := range index.Logs {
+		if tool != "" && log.Tool != tool {
+			continue
+		}
+		for _, text := range SplitLines(log.Data) {
+			if len(lines) >= max {
+				return lines, true
+			}
+			lines = append(lines, index.logLine(log.Dir, text))
+		}
+	}
+	return lines, false
+}
+
+func (index *Index) logLine(dir string, text []byte) LogLine {
+	path, lineno, _, msg, ok := ParseFileLine(text)
+	if !ok || lineno < 1 {
+		return LogLine{Text: string(text)}
+	}
+	_, _, key := index.locate(dir, string(path))
+	if _, indexed := index.Files[key]; !indexed {
+		return LogLine{Text: string(text)}
+	}
+	// without the space before the message
+	location := len(text) - len(msg) - 1
+	return LogLine{
+		Location: string(text[:location]),
+		Text:     string(text[location:]),
+		Key:      key,
+		Line:     lineno,
+	}
+}
+
+// serveLog renders the logs with links to the annotated files, like
+// /raw/log it isn't available when sharing.
+func (server *Server) serveLog(w http.ResponseWriter, r *http.Request) {
+	if server.ShareRoot != "" {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, "Logs are not shared.")
+		return
+	}
+
+	page := &LogPage{Tool: r.FormValue("tool")}
+	seen := make(map[string]bool)
+	for _, log := range server.Index.Logs {
+		if !seen[log.Tool] {
+			seen[log.Tool] = true
+			page.Tools = append(page.Tools, log.Tool)
+		}
+	}
+	page.Lines, page.Truncated = server.Index.LogLines(page.Tool, maxLogLines)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := server.Template.ExecuteTemplate(w, "log.html", page); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+}
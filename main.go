@@ -4,317 +4,514 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"html/template"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 var (
-	addr = flag.String("http", ":8080", "listen on http")
+	addr   = flag.String("http", ":8080", "listen on http")
+	header = flag.String("header", "", "header to send when log is an URL, e.g. \"Authorization: Bearer TOKEN\"")
+
+	buildCommand = flag.String("build", "", "shell command whose output is parsed like a log, e.g. \"go build -gcflags=-m ./... 2>&1\"")
+	presetName   = flag.String("preset", "", "build the packages in the current directory, or with go-test, with the compiler flags of a preset instead of -build: \"escape\" is all=-m, \"inline\" is all=-m=2, \"bce\" reports bounds checks and \"all\" is all of them")
+	reindexEvery = flag.Duration("reindex-every", 0, "rebuild the index from the logs and -build periodically, e.g. 5m")
+
+	readTimeout = flag.Duration("read-timeout", 30*time.Second, "give up reading a source file for a request after this long, 0 waits forever")
+
+	maxFileSize   = flag.Int64("max-file-size", DefaultLimits.FileSize, "show only the first lines of larger source files, 0 is no limit")
+	maxLineLength = flag.Int("max-line-length", DefaultLimits.LineLength, "cut longer source lines, 0 is no limit")
+	maxLineNotes  = flag.Int("max-line-notes", DefaultLimits.LineNotes, "show at most this many annotations of a line, 0 is no limit")
+
+	mmapLogs = flag.Bool("mmap", false, "memory-map local uncompressed logs instead of reading them, for logs larger than the RAM; the logs must not be modified while they are mapped")
+
+	historyDir = flag.String("history", "", "directory recording the annotations of each run by commit, to show the history of a line across runs")
+
+	demo = flag.Bool("demo", false, "explore the UI with an embedded sample project and its logs instead of your own")
+
+	follow = flag.Bool("follow", false, "start serving immediately and parse the logs while they are being written, e.g. piped from a running build")
+
+	format        = flag.String("format", "", "write a report to stdout instead of serving: \"html-single\" is a self-contained HTML file, \"csv\" and \"tsv\" list all annotations, \"sql\" is a script creating SQLite tables (pipe it into sqlite3 to create a database), \"warnings-ng\" is the Jenkins Warnings NG format, \"teamcity\" are TeamCity service messages, \"azdo\" are Azure Pipelines logging commands, \"snapshot\" are the metrics pushed to an aggregation server")
+	exportSources = flag.Bool("export-sources", true, "include the sources in the report written with -format")
+
+	share      = flag.Bool("share", false, "read-only sharing mode: only files inside the current directory, paths relative to it and a token is required")
+	shareToken = flag.String("share-token", "", "token for -share, a random one is generated by default")
+
+	goVersion      = flag.String("go-version", "", "Go version the logs were built with, e.g. go1.21.5, to show the matching standard library sources; detected from the logs by default")
+	downloadGoroot = flag.Bool("download-goroot", false, "download the standard library sources of the Go version of the logs with golang.org/dl when they are not installed")
+
+	analyzers        = flag.String("analyzers", "", "comma-separated go/analysis analyzers to run out of process on the packages in the current directory, e.g. printf,nilness,shadow: analyzers of go vet are run by it, others with go run of their command in golang.org/x/tools or of the import path of a singlechecker command, at the version go.mod requires or the one given with @version")
+	lintAllocations  = flag.Bool("lint-allocs", false, "add annotations about fmt.Sprintf and string concatenation in loops to the annotated packages, as the tool \"alloclint\"")
+	excludeGenerated = flag.Bool("exclude-generated", false, "ignore annotations of generated files")
+
+	memprofile  = flag.String("memprofile", "", "heap profile used to rank escape sites by allocated bytes")
+	codeOwners  = flag.String("codeowners", "", "CODEOWNERS file to group the annotations by owner, found in the current directory by default")
+	explainFile = flag.String("explain", "", "YAML file with explanations of compiler messages, \"- match: substring\" with title, explanation, fix and link, used before the built-in ones")
+	weights     = flag.String("weights", "", "file with the weights of packages, \"import/path weight\" per line, to rank the annotations of hot packages first")
+
+	fileOrder = flag.String("sort", "path", "default order of the file list: \"path\", \"notes\" or \"escapes\" with the most first, or \"changed\" with the most recently committed first; the order chosen in the UI is remembered by the browser")
+
+	assetsDir = flag.String("assets", "", "directory with UI files overriding the embedded ones")
+	indexTmpl = flag.String("template", "", "html/template file replacing the index page")
+	fileTmpl  = flag.String("file-template", "", "html/template file rendering a file at /view?path=")
+
+	corsOrigins StringList
+	roots       StringList
+	metadata    StringList
+	reviews     StringList
 )
 
+func init() {
+	flag.Var(&roots, "root", "checkout to resolve relative paths in logs against, can be repeated to serve several projects")
+	flag.Var(&metadata, "meta", "build metadata recorded with the index, e.g. commit=SHA, goos=linux or gcflags=-m, can be repeated; go, goos, goarch, gcflags and commit are detected when possible")
+	flag.Var(&reviews, "review", "URL of a GitHub pull request or Gerrit change whose review comments are shown with the annotations, can be repeated; uses $GITHUB_TOKEN or $GERRIT_USER and $GERRIT_PASSWORD")
+	flag.Var(&corsOrigins, "cors-origin", "allow cross-origin API requests from origin, can be repeated, \"*\" allows any origin")
+}
+
+// StringList is a flag that can be specified multiple times.
+type StringList []string
+
+func (list *StringList) String() string { return strings.Join(*list, ",") }
+
+func (list *StringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
 func main() {
 	flag.Parse()
-	var rd io.Reader = os.Stdin
-	if flag.Arg(0) != "" {
-		file, err := os.Open(flag.Arg(0))
+
+	dir, _ := filepath.Abs(".")
+	index := newIndex()
+	var followed []string
+	var goTest []string
+	var bundle *ReleaseBundle
+	var daemon *Daemon
+	var indexed []string // inputs parsed again when reindexing
+
+	if err := CheckFileOrder(*fileOrder); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	var preset Preset
+	if *presetName != "" {
+		var err error
+		preset, err = FindPreset(*presetName)
+		if err == nil && *buildCommand != "" {
+			err = fmt.Errorf("-preset cannot be combined with -build")
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%v\n", err)
 			os.Exit(1)
 		}
-		defer file.Close()
-		rd = file
+		if flag.Arg(0) != "go-test" {
+			*buildCommand = preset.BuildCommand()
+		}
+	}
+
+	switch flag.Arg(0) {
+	case "version":
+		PrintVersion(os.Stdout)
+		return
+	case "self-update":
+		if err := SelfUpdate(flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "bench-compare":
+		if err := BenchCompare(flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "bisect":
+		if err := Bisect(flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(BisectExitCode(err))
+		}
+		return
+	case "check":
+		if err := Check(flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "badge":
+		if err := Badge(flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "inline-budget":
+		if err := InlineBudget(flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "aggregate":
+		if err := Aggregate(flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "publish-gerrit":
+		if err := PublishGerrit(flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "publish-bitbucket":
+		if err := PublishBitbucket(flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "fetch-gha":
+		data, err := FetchGHA(flag.Args()[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		index.Parse(dir, "", data)
+	case "release-bundle":
+		var err error
+		bundle, err = ParseReleaseBundle(flag.Args()[1:], dir)
+		if err == nil {
+			indexed = bundle.Inputs
+			if len(indexed) == 0 && *buildCommand == "" && *analyzers == "" {
+				indexed = []string{""}
+			}
+			err = loadIndex(index, dir, indexed)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	case "daemon":
+		if *buildCommand == "" {
+			*buildCommand = presets[0].BuildCommand()
+		}
+		var err error
+		daemon, err = ParseDaemon(flag.Args()[1:], *buildCommand)
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "Building the modules in %v\n", daemon.Workspace)
+			_, err = daemon.Update()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		dir = daemon.Workspace
+		index = daemon.Index()
+	case "go-test":
+		goTest = flag.Args()[1:]
+		if preset.Gcflags != "" {
+			if len(goTest) == 0 {
+				goTest = []string{"./..."}
+			}
+			// the last -gcflags wins, so the arguments can still override it
+			goTest = append([]string{"-gcflags=" + preset.Gcflags}, goTest...)
+		}
+	default:
+		if *demo {
+			if flag.NArg() > 0 || *buildCommand != "" || *follow {
+				fmt.Fprintf(os.Stderr, "-demo cannot be combined with logs, -build or -follow\n")
+				os.Exit(1)
+			}
+			dir = DemoDir
+			if err := LoadDemo(index); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			break
+		}
+		inputs := flag.Args()
+		if len(inputs) == 0 && *buildCommand == "" && *analyzers == "" && len(reviews) == 0 {
+			inputs = []string{""}
+		}
+		if *follow {
+			// -build, -analyzers and -review run once, the inputs are followed
+			followed = inputs
+			if err := loadIndex(index, dir, nil); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			break
+		}
+		indexed = inputs
+		if err := loadIndex(index, dir, inputs); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	command := *buildCommand
+	if goTest != nil {
+		command = "go " + strings.Join(goTestArgs(goTest), " ")
+	}
+	if err := finishIndex(index, dir, command); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
 	}
 
-	data, err := ioutil.ReadAll(rd)
+	tmpl, err := LoadTemplate(*assetsDir)
+	if err == nil && *indexTmpl != "" {
+		err = ParseTemplateFile(tmpl, "index.html", *indexTmpl)
+	}
+	if err == nil && *fileTmpl != "" {
+		err = ParseTemplateFile(tmpl, "file.html", *fileTmpl)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 
-	index := NewIndex()
+	server := &Server{
+		Index:       index,
+		Template:    tmpl,
+		CORSOrigins: corsOrigins,
+		Live:        followed != nil || goTest != nil || daemon != nil,
+		ReadTimeout: *readTimeout,
+		Order:       *fileOrder,
+		Demo:        *demo,
+
+		LintAllocations:  *lintAllocations,
+		ExcludeGenerated: *excludeGenerated,
+	}
+	if *explainFile != "" {
+		data, err := ReadInput(*explainFile)
+		if err == nil {
+			server.Explanations, err = ParseExplanations(data)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", *explainFile, err)
+			os.Exit(1)
+		}
+		server.Explanations = append(server.Explanations, explanations...)
+	}
+	if *historyDir != "" {
+		server.History, err = OpenHistory(*historyDir)
+		if err == nil && (!server.Live || daemon != nil) {
+			err = server.History.Record(index)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	if bundle != nil {
+		if err := bundle.Write(server, *exportSources); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %v\n", bundle.Output)
+		return
+	}
+	if *format != "" {
+		if goTest != nil {
+			err := server.GoTest(dir, goTest)
+			if err == nil && server.History != nil {
+				err = server.History.Record(server.Index)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+		}
+		if err := writeReport(os.Stdout, server, *format); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	dir, _ := filepath.Abs(".")
-	index.Parse(dir, data)
+	if *share {
+		server.ShareRoot = dir
+		server.ShareToken = *shareToken
+		if server.ShareToken == "" {
+			server.ShareToken = NewShareToken()
+		}
+		fmt.Printf("Sharing at http://localhost%v/?token=%v\n", *addr, server.ShareToken)
+	}
+	if *reindexEvery > 0 {
+		if indexed == nil {
+			fmt.Fprintf(os.Stderr, "-reindex-every needs logs or -build\n")
+			os.Exit(1)
+		}
+		server.ReindexEvery = *reindexEvery
+		go server.Reindex(func() (*Index, error) {
+			index := newIndex()
+			err := loadIndex(index, dir, indexed)
+			if err == nil {
+				err = finishIndex(index, dir, *buildCommand)
+			}
+			if err == nil && server.History != nil {
+				err = server.History.Record(index)
+			}
+			return index, err
+		})
+	}
+	if followed != nil {
+		go server.Follow(dir, followed)
+	}
+	if daemon != nil {
+		server.ReindexEvery = daemon.Poll
+		go daemon.Watch(server)
+	}
+	if goTest != nil {
+		go func() {
+			if err := server.GoTest(dir, goTest); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+			}
+		}()
+	}
 
 	fmt.Printf("Listening on %v\n", *addr)
-	err = http.ListenAndServe(*addr, &Server{index})
+	err = http.ListenAndServe(*addr, server)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 }
 
-type Server struct {
-	Index *Index
+// writeReport writes the index in format instead of serving it.
+func writeReport(w io.Writer, server *Server, format string) error {
+	switch format {
+	case "html-single":
+		return server.ExportHTML(w, *exportSources)
+	case "csv":
+		return server.Index.WriteTable(w, ',')
+	case "tsv":
+		return server.Index.WriteTable(w, '\t')
+	case "sql":
+		return server.Index.WriteSQL(w)
+	case "warnings-ng":
+		return server.Index.WriteWarningsNG(w)
+	case "teamcity":
+		return server.Index.WriteTeamCity(w)
+	case "azdo":
+		return server.Index.WriteAzureDevOps(w)
+	case "snapshot":
+		return json.NewEncoder(w).Encode(server.Index.Snapshot())
+	}
+	return fmt.Errorf("unknown format %q", format)
 }
 
-func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path == "" || r.URL.Path == "/" {
-		err := T.Execute(w, map[string]interface{}{
-			"StatCount": statCount,
-			"Stats":     statSpecs,
-			"Files":     server.Index.Files,
-		})
+// newIndex returns an empty index for the -root flags.
+func newIndex() *Index {
+	index := NewIndex()
+	index.GoVersion = *goVersion
+	index.Limits = Limits{
+		FileSize:   *maxFileSize,
+		LineLength: *maxLineLength,
+		LineNotes:  *maxLineNotes,
+	}
+	for _, root := range roots {
+		abs, err := filepath.Abs(root)
 		if err != nil {
-			w.WriteHeader(http.StatusOK)
-			fmt.Fprintf(os.Stderr, "%v\n", err)
+			abs = root
 		}
-		return
+		index.Roots = append(index.Roots, abs)
 	}
+	return index
+}
 
-	if r.URL.Path == "/file" {
-		path := r.FormValue("path")
-		if path == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintf(w, "No path specified.")
-			return
+// loadIndex parses the logs in inputs and the output of -build and
+// -analyzers, and adds the comments of -review.
+func loadIndex(index *Index, dir string, inputs []string) error {
+	for _, input := range inputs {
+		tool, name := SplitInput(input)
+		read := ReadInput
+		if *mmapLogs {
+			read = index.MapInput
 		}
-
-		annotated, err := server.Index.LoadAnnotatedFile(path)
+		data, err := read(name)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprintf(os.Stderr, "%v\n", err)
-			fmt.Fprintf(w, "Error: %v", err)
-			return
+			return err
 		}
-
-		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-
-		err = json.NewEncoder(w).Encode(annotated)
+		index.Parse(index.LogRoot(dir, name), tool, data)
+	}
+	if *buildCommand != "" {
+		data, err := BuildOutput(dir, *buildCommand)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return err
 		}
-		return
+		index.Parse(dir, "", data)
 	}
-
-	w.WriteHeader(http.StatusNotFound)
+	if *analyzers != "" {
+		data, err := RunAnalyzers(dir, strings.Split(*analyzers, ","))
+		if err != nil {
+			return err
+		}
+		index.Parse(dir, "vet", data)
+	}
+	for _, review := range reviews {
+		system, comments, err := FetchReviewComments(review)
+		if err != nil {
+			return err
+		}
+		index.AddReviewComments(dir, system, comments)
+	}
+	return nil
 }
 
-var T = template.Must(template.New("").Funcs(template.FuncMap{
-	"mul": func(a, b int) int { return a * b },
-}).Parse(`
-<html>
-<body>
-	<select id="file" onchange="fileSelected()">
-		{{ range .Files }}
-		<option value="{{.Path}}">{{.AbsPath}} {{.Stats}}</option>
-		{{ end }}
-	</select>
-	<div id="source">
-	</div>
-
-	<style>
-	.line {
-		position: relative;
-		height: 1.2em;
-		overflow: hidden;
-
-		--number-width: 3em;
-		--info-width: 20em;
-		--tags-width: {{mul .StatCount 2}}em;
-
-		contain: strict;
-	}
-	.line:hover {
-		background: #eee;
-	}
-	
-	.line .number {
-		position: absolute;
-		display: block;
-		left: 0; right: 0; top: 0; bottom: 0;
-		width: var(--number-width);
-	}
-	.line .source {
-		position: absolute;
-		display: block;
-		white-space: pre;
-		left: var(--number-width);
-		right: calc(var(--info-width) + var(--tags-width));
-		top: 0; bottom: 0;
-		text-overflow: ellipsis;
-		overflow: hidden;
-	}
-	.line .source .tip {
-		display: inline-block;
-		width: 5px;
-		background: #aaa;
-	}
-	.line .info {
-		position: absolute;
-		display: block;
-		right: var(--tags-width); top: 0; bottom: 0;
-		width: var(--info-width);
-		text-overflow: ellipsis;
-		overflow: hidden;
-	}
-	.line .tags {
-		position: absolute;
-		height: 1.2em;
-		display: block;
-		right: 0;
-		width: var(--tags-width);
-	}
-	.line .tag {
-		position: absolute;
-		display: block;
-		top: 0; bottom: 0;
-		width: 2em;
-		overflow: hidden;
-		border: 1px solid #eee;
-
-		text-align: center;
-	}
-	.line .tag .good { display: inline-block; width: 0.8em; color: #aaa; }
-	.line .tag .bad  { display: inline-block; width: 0.8em; color: #aaa; }
-	
-	.line .tag .active.good { color: #000; background: #dfd; }
-	.line .tag .active.bad  { color: #000; background: #fdd; }
-
-	{{ range $index, $stat := .Stats }}
-	.line .tag-{{$index}} { left: {{mul $index 2}}em; }	
-	{{ end }}
-	</style>
-
-	<script>
-		var pending = null;
-		function fileSelected() {
-			if(pending){
-				pending.abort();
-			}
-			var el = document.getElementById("file")
-			if(el.value != ""){
-				pending = fetch("/file?path=" + encodeURI(el.value))
-					.then(function(response){
-						pending = null;
-						if(response.ok){
-							response.json().then(updateSource);
-						}
-					})
-			}
+// finishIndex applies the flags that process the parsed index, command is
+// the build run locally to produce the logs, if any.
+func finishIndex(index *Index, dir string, command string) error {
+	index.Metadata = CollectMetadata(index, dir, command)
+	index.Metadata.Preset = *presetName
+	for _, meta := range metadata {
+		if err := index.Metadata.Set(meta); err != nil {
+			return err
 		}
+	}
 
-		function updateSource(file) {
-			var fragment = document.createDocumentFragment();
-			file.lines.forEach((line, index) => {
-				var lineel = h("div", "line");
-				lineel.appendChild(h("span", "number", index + 1));
-
-				var source = h("span", "source");
-				var p = 0;
-				var noteIndex = 0;
-				while(noteIndex < line.notes.length){
-					var note = line.notes[noteIndex];
-					if(note.column < 0){
-						noteIndex++;
-						continue;
-					}
-					var text = line.source.substr(p, note.column - p);
-					source.appendChild(document.createTextNode(text));
-					p = note.column;
-					noteIndex++;
-
-					var tip = document.createElement("span");
-					tip.className = "tip";
-					tip.title = note.message;
-					tip.innerText = " ";
-					while((noteIndex < line.notes.length) && (line.notes[noteIndex].column == p)){
-						tip.title += "\n" + line.notes[noteIndex].message;
-						noteIndex++;
-					}
-					source.appendChild(tip);
-				}
-				source.appendChild(document.createTextNode(line.source.substr(p)));
-				lineel.appendChild(source);
-	
-				var fullinfo = "";
-				if(line.notes.length > 0){
-					var infoel = h("span", "info", line.notes[0].message);
-					line.notes.forEach(note => {
-						fullinfo += note.message + "\n";
-					});
-					infoel.title = fullinfo;
-					lineel.appendChild(infoel);
-				}
-
-				var tags = h("span", "tags");
-				lineel.appendChild(tags);
-
-				function addtag(i, good, bad){
-					var goodCount = 0;
-					var badCount = 0;
-					
-					line.notes.forEach(note => {
-						good.forEach(keyword => {
-							if(note.message.indexOf(keyword) >= 0){
-								goodCount++;
-							}
-						});
-						bad.forEach(keyword => {
-							if(note.message.indexOf(keyword) >= 0){
-								badCount++;
-							}
-						});
-					})
-
-					if(goodCount + badCount > 0){
-						var goodel = h("span", "good", goodCount);
-						if(goodCount > 0) goodel.className += " active";
-						goodel.title = good.join("\n");
-						
-						var badel = h("span", "bad", badCount);
-						if(badCount > 0) badel.className += " active";
-						badel.title = bad.join("\n");
-
-						var el = h("span", "tag active tag-" + i, [
-							goodel, "/", badel
-						]);
-						tags.appendChild(el);
-					} else {
-						tags.appendChild(h("span", "tag tag-" + i), "");
-					}
-				}
-
-				{{range $index, $stat := .Stats }}
-				addtag({{$index}}, {{$stat.Good}}, {{$stat.Bad}});
-				{{end}}
-
-				fragment.appendChild(lineel);
-			});
-
-			var source = document.getElementById("source");
-			source.innerText = "";
-			source.appendChild(fragment);
-		}
-
-		function h(tag, className, children){
-			var el = document.createElement(tag);
-			el.className = className;
-
-			if((typeof children == "string") || (typeof children == "number")){
-				children = [children];
-			} else if (typeof children == "undefined") {
-				children = [];
-			}
+	if *lintAllocations {
+		index.LintAllocations()
+	}
 
-			for(var i = 0; i < children.length; i++){
-				var child = children[i];
-				if(typeof child === "string" || typeof child == "number"){
-					el.appendChild(document.createTextNode(child));
-				} else {
-					el.appendChild(child);
-				}
-			}
-			return el;
+	if *excludeGenerated {
+		index.ExcludeGenerated()
+	}
+
+	if *share {
+		index.Share(dir)
+	}
+
+	if path := *codeOwners; path != "" || FindCodeOwners(dir) != "" {
+		if path == "" {
+			path = FindCodeOwners(dir)
+		}
+		owners, err := LoadCodeOwners(path)
+		if err != nil {
+			return err
 		}
+		index.CodeOwners = owners
+	}
+
+	if *weights != "" {
+		data, err := ReadInput(*weights)
+		if err == nil {
+			index.Weights, err = ParseWeights(dir, data)
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %v", *weights, err)
+		}
+	}
 
-		fileSelected();
-	</script>
-</body>
-</html>
-`))
+	if *memprofile != "" {
+		data, err := ReadInput(*memprofile)
+		if err == nil {
+			err = index.LoadProfile(dir, data)
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %v", *memprofile, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Metadata describes the build the logs come from, so that reports are
+// self-describing and can be compared.
+type Metadata struct {
+	GoVersion string `json:"goVersion,omitempty"`
+	GOOS      string `json:"goos,omitempty"`
+	GOARCH    string `json:"goarch,omitempty"`
+	Gcflags   string `json:"gcflags,omitempty"`
+	// Preset is the -preset the logs were built with.
+	Preset string `json:"preset,omitempty"`
+	Commit string `json:"commit,omitempty"`
+	// Release is the version of a release-bundle.
+	Release string    `json:"release,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// gcflagsPattern finds -gcflags in a build command, quoted as a whole or
+// only its value.
+var gcflagsPattern = regexp.MustCompile(`"-gcflags=([^"]*)"|'-gcflags=([^']*)'|-gcflags[= ]('[^']*'|"[^"]*"|\S+)`)
+
+// CollectMetadata returns the metadata of the logs in the index, parsed in
+// dir. The platform and Go version are only known from the logs, unless
+// command is the build that was run locally.
+func CollectMetadata(index *Index, dir string, command string) Metadata {
+	meta := Metadata{
+		GoVersion: index.GoVersion,
+		Commit:    gitCommit(dir),
+		Time:      index.Modified.UTC(),
+	}
+	if index.Modified.IsZero() {
+		// the logs are still to be parsed, e.g. with go-test
+		meta.Time = time.Now().UTC()
+	}
+	if command != "" {
+		if meta.GoVersion == "" {
+			meta.GoVersion = gorootVersion(localGoroot())
+		}
+		meta.GOOS, meta.GOARCH = goEnv("GOOS", runtime.GOOS), goEnv("GOARCH", runtime.GOARCH)
+		// the last -gcflags wins, like in the go command
+		if m := gcflagsPattern.FindAllStringSubmatch(command, -1); m != nil {
+			last := m[len(m)-1]
+			meta.Gcflags = strings.Trim(last[1]+last[2]+last[3], `'"`)
+		}
+	}
+	return meta
+}
+
+// goEnv returns the environment variable for the go command, or def.
+func goEnv(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
+
+// gitCommit returns the commit checked out in dir, or the one of the CI run.
+func gitCommit(dir string) string {
+	for _, name := range []string{"GITHUB_SHA", "CI_COMMIT_SHA", "BUILD_VCS_NUMBER", "GIT_COMMIT"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	commit, _ := gitOutput(dir, "rev-parse", "HEAD")
+	return commit
+}
+
+// Set sets a field from a -meta key=value flag.
+func (meta *Metadata) Set(flag string) error {
+	eq := strings.IndexByte(flag, '=')
+	if eq < 0 {
+		return fmt.Errorf("invalid metadata %q, expected key=value", flag)
+	}
+	key, value := flag[:eq], flag[eq+1:]
+	switch key {
+	case "go":
+		meta.GoVersion = value
+	case "goos":
+		meta.GOOS = value
+	case "goarch":
+		meta.GOARCH = value
+	case "gcflags":
+		meta.Gcflags = value
+	case "commit":
+		meta.Commit = value
+	case "release":
+		meta.Release = value
+	default:
+		return fmt.Errorf("unknown metadata %q, expected go, goos, goarch, gcflags, commit or release", key)
+	}
+	return nil
+}
+
+// String summarizes the metadata on one line.
+func (meta Metadata) String() string {
+	var parts []string
+	if meta.Release != "" {
+		parts = append(parts, meta.Release)
+	}
+	if meta.GoVersion != "" {
+		parts = append(parts, meta.GoVersion)
+	}
+	if meta.GOOS != "" || meta.GOARCH != "" {
+		parts = append(parts, meta.GOOS+"/"+meta.GOARCH)
+	}
+	if meta.Preset != "" {
+		parts = append(parts, "-preset="+meta.Preset)
+	} else if meta.Gcflags != "" {
+		parts = append(parts, "-gcflags="+meta.Gcflags)
+	}
+	if meta.Commit != "" {
+		commit := meta.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		parts = append(parts, commit)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"os"
+)
+
+// MapInput returns the log name like ReadInput, but memory-maps local
+// uncompressed files instead of reading them, so that logs larger than the
+// RAM can be indexed: the notes refer to the mapped log, whose pages the
+// kernel loads when they are needed. The mapping is released by Close.
+func (index *Index) MapInput(name string) ([]byte, error) {
+	data, err := mapInput(name)
+	if data == nil || err != nil {
+		return ReadInput(name)
+	}
+	index.mapped = append(index.mapped, data)
+	return data, nil
+}
+
+// mapInput maps name, returns nil when it can't be mapped.
+func mapInput(name string) ([]byte, error) {
+	if name == "" || IsURL(name) {
+		return nil, nil
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 {
+		return nil, err
+	}
+
+	data, err := mapFile(file, info.Size())
+	if err != nil {
+		return nil, nil
+	}
+	if bytes.HasPrefix(data, gzipMagic) || bytes.HasPrefix(data, zstdMagic) {
+		unmapFile(data)
+		return nil, nil
+	}
+	return data, nil
+}
+
+// Close releases the logs mapped by MapInput, the notes of the index must
+// not be used afterwards.
+func (index *Index) Close() {
+	for _, data := range index.mapped {
+		unmapFile(data)
+	}
+	index.mapped = nil
+}
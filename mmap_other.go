@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || freebsd || openbsd || netbsd || dragonfly)
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+func mapFile(file *os.File, size int64) ([]byte, error) {
+	return nil, errors.New("memory-mapping is not supported on this platform")
+}
+
+func unmapFile(data []byte) error {
+	return nil
+}
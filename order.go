@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileOrders are the orders of the file list, the first is the default.
+var fileOrders = []string{"path", "notes", "escapes", "changed"}
+
+// CheckFileOrder returns an error when order isn't one of fileOrders.
+func CheckFileOrder(order string) error {
+	for _, known := range fileOrders {
+		if order == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown order %q, expected %s", order, strings.Join(fileOrders, ", "))
+}
+
+// OrderedFiles returns all files in order: "path", "notes" with the most
+// annotations first, "escapes" with the most heap escapes first or "changed"
+// with the most recently committed first. Ties are sorted by path.
+func (index *Index) OrderedFiles(order string) []*File {
+	files := index.SortedFiles()
+	var key func(file *File) int64
+	switch order {
+	case "notes":
+		key = func(file *File) int64 { return int64(len(file.Notes)) }
+	case "escapes":
+		key = func(file *File) int64 { return int64(file.Stats[1][1]) }
+	case "changed":
+		changed := index.ChangeTimes()
+		key = func(file *File) int64 { return changed[file.Key].Unix() }
+	default:
+		return files
+	}
+	sort.SliceStable(files, func(i, k int) bool {
+		return key(files[i]) > key(files[k])
+	})
+	return files
+}
+
+// FileGroup are the files of a directory in the file list, Dir is empty
+// when the files aren't grouped.
+type FileGroup struct {
+	Dir   string
+	Files []*File
+}
+
+// GroupFiles returns the files in order, grouped by directory when they
+// are sorted by path.
+func (index *Index) GroupFiles(order string) []FileGroup {
+	files := index.OrderedFiles(order)
+	if order != "path" {
+		return []FileGroup{{Files: files}}
+	}
+	groups := []FileGroup{}
+	for _, file := range files {
+		dir := filepath.Dir(file.Path)
+		if len(groups) == 0 || groups[len(groups)-1].Dir != dir {
+			groups = append(groups, FileGroup{Dir: dir})
+		}
+		last := &groups[len(groups)-1]
+		last.Files = append(last.Files, file)
+	}
+	return groups
+}
+
+// maxChangeCommits limits the history searched for the last change of files.
+const maxChangeCommits = 10000
+
+// ChangeTimes returns the time of the last commit changing each file by
+// index key, files without a commit in the checkouts of the logs are missing.
+// The times are found once per generation of the index.
+func (index *Index) ChangeTimes() map[string]time.Time {
+	if index.changed != nil && index.changedGeneration == index.Generation {
+		return index.changed
+	}
+	index.changed = make(map[string]time.Time)
+	index.changedGeneration = index.Generation
+
+	seen := make(map[string]bool)
+	dirs := append([]string{}, index.Roots...)
+	for _, log := range index.Logs {
+		dirs = append(dirs, log.Dir)
+	}
+	for _, dir := range dirs {
+		top, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+		if err != nil || seen[top] {
+			continue
+		}
+		seen[top] = true
+		index.gitChangeTimes(top)
+	}
+	return index.changed
+}
+
+// gitChangeTimes adds the last commit times of the indexed files in the
+// checkout at top, stopping when all of them were found.
+func (index *Index) gitChangeTimes(top string) {
+	// the slash paths relative to top of the files still missing
+	missing := make(map[string]string)
+	root := index.CanonicalPath("", top)
+	for key := range index.Files {
+		if _, ok := index.changed[key]; ok {
+			continue
+		}
+		if rel, err := filepath.Rel(root, key); err == nil && !strings.HasPrefix(rel, "..") {
+			missing[filepath.ToSlash(rel)] = key
+		}
+	}
+
+	if len(missing) == 0 {
+		return
+	}
+
+	cmd := exec.Command("git", "-c", "core.quotePath=false", "log", "-n", strconv.Itoa(maxChangeCommits), "--no-renames", "--name-only", "--format=%x00%ct")
+	cmd.Dir = top
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		return
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	var commit time.Time
+	scanner := bufio.NewScanner(out)
+	for len(missing) > 0 && scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		if line[0] == 0 {
+			seconds, _ := strconv.ParseInt(line[1:], 10, 64)
+			commit = time.Unix(seconds, 0)
+			continue
+		}
+		if caseInsensitive {
+			line = strings.ToLower(line)
+		}
+		if key, ok := missing[line]; ok {
+			index.changed[key] = commit
+			delete(missing, line)
+		}
+	}
+}
+
+// gitOutput runs git in dir and returns its trimmed output.
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	return string(bytes.TrimSpace(out)), err
+}
+
+// repoRoot returns the root of the git repository checked out in dir with
+// symlinks resolved, or dir outside of a repository.
+func repoRoot(dir string) string {
+	root := dir
+	if top, err := gitOutput(dir, "rev-parse", "--show-toplevel"); err == nil {
+		root = top
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	return root
+}
+
+// repoPath returns the slash-separated path of a file relative to root, as
+// code review systems identify files.
+func repoPath(root string, path string) (string, error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%v is outside of %v", path, root)
+	}
+	return filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Parser parses a multi-line input format, such as race reports or
+// stack traces. Lines not detected by any parser are handled by Index.Add.
+type Parser interface {
+	// Detect reports whether line starts input handled by the parser.
+	Detect(line []byte) bool
+	// Parse adds notes from the input starting at lines[at] to the index,
+	// returns the index of the last line it consumed.
+	Parse(index *Index, dir string, lines [][]byte, at int) int
+}
+
+// InputParser parses a format that is only recognized as a whole input,
+// such as a JSON document or a binary file, instead of line by line.
+type InputParser interface {
+	// DetectInput reports whether data is in the format of the parser.
+	DetectInput(data []byte) bool
+	// ParseInput adds the notes of data to the index.
+	ParseInput(index *Index, dir string, data []byte) error
+}
+
+// registeredParser is a parser in the registry, either Parser or input is
+// set, see RegisterParser and RegisterInputParser.
+type registeredParser struct {
+	Parser
+	input    InputParser
+	priority int
+}
+
+// name returns the type of the parser for messages.
+func (parser registeredParser) name() string {
+	if parser.input != nil {
+		return fmt.Sprintf("%T", parser.input)
+	}
+	return fmt.Sprintf("%T", parser.Parser)
+}
+
+// parsers are the registered parsers by priority.
+var parsers []registeredParser
+
+// RegisterParser adds a parser to the registry. Parsers with a lower
+// priority take precedence when several detect the same line, formats whose
+// lines look like those of another format need a lower priority than it.
+// The built-in parsers use multiples of 10. Priorities are unique among all
+// parsers, including input parsers, so that the precedence doesn't depend on
+// the order files are initialized in.
+func RegisterParser(priority int, parser Parser) {
+	register(registeredParser{Parser: parser, priority: priority})
+}
+
+// RegisterInputParser adds a parser of whole inputs to the registry. Inputs
+// are detected by the input parsers, by priority like RegisterParser, before
+// their lines are detected by the other parsers.
+func RegisterInputParser(priority int, parser InputParser) {
+	register(registeredParser{input: parser, priority: priority})
+}
+
+func register(parser registeredParser) {
+	i := sort.Search(len(parsers), func(i int) bool { return parsers[i].priority >= parser.priority })
+	if i < len(parsers) && parsers[i].priority == parser.priority {
+		panic(fmt.Sprintf("parsers %v and %v have the same priority %d", parsers[i].name(), parser.name(), parser.priority))
+	}
+	parsers = append(parsers, registeredParser{})
+	copy(parsers[i+1:], parsers[i:])
+	parsers[i] = parser
+}
+
+// DetectInputParser returns the parser for the whole input data, or nil
+// when it is parsed line by line.
+func DetectInputParser(data []byte) InputParser {
+	for _, parser := range parsers {
+		if parser.input != nil && parser.input.DetectInput(data) {
+			return parser.input
+		}
+	}
+	return nil
+}
+
+// DetectParser returns the parser for input starting with line,
+// or nil when line should be handled as a plain diagnostic.
+func DetectParser(line []byte) Parser {
+	for _, parser := range parsers {
+		if parser.Parser != nil && parser.Detect(line) {
+			return parser.Parser
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParserPriorities(t *testing.T) {
+	for i := 1; i < len(parsers); i++ {
+		if parsers[i-1].priority >= parsers[i].priority {
+			t.Errorf("%v (%d) is before %v (%d)", parsers[i-1].name(), parsers[i-1].priority, parsers[i].name(), parsers[i].priority)
+		}
+	}
+}
+
+// checkParser checks that input is detected by parser and that it adds the
+// notes, "path:line:column: tool: message", the column is 0 when there is
+// none. Paths in input are relative to /src.
+func checkParser(t *testing.T, parser Parser, input string, notes []string) {
+	t.Helper()
+	lines := SplitLines([]byte(strings.TrimPrefix(input, "\n")))
+	// e.g. the input of semgrep starts with the file, which isn't detected
+	var detected Parser
+	for _, line := range lines {
+		if detected = DetectParser(line); detected != nil {
+			break
+		}
+	}
+	if detected != parser {
+		t.Fatalf("input detected by %T", detected)
+	}
+
+	index := NewIndex()
+	index.Sources = fstest.MapFS{}
+	index.ParseLines("/src", "compiler", lines)
+	index.Sort()
+	checkParsedNotes(t, index, notes)
+}
+
+// checkInputParser is checkParser for parsers of whole inputs.
+func checkInputParser(t *testing.T, parser InputParser, data []byte, notes []string) {
+	t.Helper()
+	if detected := DetectInputParser(data); detected != parser {
+		t.Fatalf("input detected by %T", detected)
+	}
+
+	index := NewIndex()
+	index.Sources = fstest.MapFS{}
+	index.Parse("/src", "compiler", data)
+	checkParsedNotes(t, index, notes)
+}
+
+func checkParsedNotes(t *testing.T, index *Index, want []string) {
+	t.Helper()
+	var notes []string
+	for _, file := range index.SortedFiles() {
+		for _, note := range file.Notes {
+			notes = append(notes, fmt.Sprintf("%v:%v:%v: %v: %s", file.Path, note.Line+1, note.Column+1, note.Tool, note.Message))
+		}
+	}
+	if strings.Join(notes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got notes\n\t%v\nwant\n\t%v", strings.Join(notes, "\n\t"), strings.Join(want, "\n\t"))
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// PoolCandidate is a type whose values escape to the heap at several sites
+// or where the heap profile shows allocations, so reusing its values with
+// sync.Pool may save allocations. The advice is experimental: whether a
+// value can be reused depends on its lifetime, which isn't checked.
+type PoolCandidate struct {
+	Type string `json:"type"`
+	// Bytes were allocated at the escape sites according to -memprofile.
+	Bytes int64 `json:"bytes"`
+	// Weight is the sum of the weights of the packages of the sites, the
+	// number of sites without -weights.
+	Weight float64      `json:"weight"`
+	Sites  []EscapeSite `json:"sites"`
+}
+
+// PoolCandidates returns the types of escaping values that could be pooled,
+// pointers, slices and maps, escaping at more than one site or allocating
+// in the heap profile. They are ranked by allocated bytes, then by the
+// weight of their sites. The types are those of EscapesByType, which are
+// found once per generation of the index.
+func (index *Index) PoolCandidates() []PoolCandidate {
+	candidates := []PoolCandidate{}
+	for _, group := range index.EscapesByType() {
+		if !poolable(group.Type) {
+			continue
+		}
+		candidate := PoolCandidate{Type: group.Type, Sites: group.Sites}
+		counted := make(map[EscapeSite]bool)
+		for _, site := range group.Sites {
+			file := index.Files[site.Key]
+			candidate.Weight += index.Weights.Weight(file.AbsPath)
+			// the bytes of a line count once for its messages
+			line := EscapeSite{Key: site.Key, Line: site.Line}
+			if !counted[line] {
+				counted[line] = true
+				candidate.Bytes += index.Allocated[site.Key][site.Line]
+			}
+		}
+		if len(candidate.Sites) > 1 || candidate.Bytes > 0 {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, k int) bool {
+		a, b := &candidates[i], &candidates[k]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Weight > b.Weight
+	})
+	return candidates
+}
+
+// poolable reports whether values of the type, as formatted by
+// EscapesByType, can be reused: pointers, slices and maps, except
+// pointers to basic types.
+func poolable(typ string) bool {
+	switch {
+	case strings.HasPrefix(typ, "[]"), strings.HasPrefix(typ, "map["):
+		return true
+	case strings.HasPrefix(typ, "*"):
+		_, basic := types.Universe.Lookup(typ[1:]).(*types.TypeName)
+		return !basic
+	}
+	return false
+}
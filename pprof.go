@@ -0,0 +1,314 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Allocation is an escape site with the bytes allocated there
+// according to a heap profile.
+type Allocation struct {
+	Key      string   `json:"key"`
+	Path     string   `json:"path"`
+	Line     int      `json:"line"` // 1 is the first line
+	Bytes    int64    `json:"bytes"`
+	Messages []string `json:"messages"`
+	// Weight is the weight of the package with -weights.
+	Weight float64 `json:"weight"`
+}
+
+// allocationKeywords select notes about heap allocations.
+var allocationKeywords = []string{"escapes to heap", "moved to heap"}
+
+// LoadProfile reads allocated bytes per source line from a pprof heap profile.
+func (index *Index) LoadProfile(dir string, data []byte) error {
+	samples, err := ParseProfile(data, "alloc_space")
+	if err != nil {
+		return err
+	}
+
+	index.Allocated = make(map[string]map[int]int64)
+	for _, sample := range samples {
+		key := index.CanonicalPath(dir, sample.File)
+		lines, ok := index.Allocated[key]
+		if !ok {
+			lines = make(map[int]int64)
+			index.Allocated[key] = lines
+		}
+		lines[sample.Line] += sample.Value
+	}
+	return nil
+}
+
+// Allocations returns escape sites ranked by allocated bytes.
+func (index *Index) Allocations() []Allocation {
+	return index.Sites(allocationKeywords)
+}
+
+// Sites returns the lines with notes containing any of the keywords,
+// ranked by allocated bytes, the weight of their package and then by path
+// and line.
+func (index *Index) Sites(keywords []string) []Allocation {
+	allocations := []Allocation{}
+	for key, file := range index.Files {
+		weight := index.Weights.Weight(file.AbsPath)
+		byLine := make(map[int]int)
+		for _, note := range file.Notes {
+			if !containsAny(string(note.Message), keywords) {
+				continue
+			}
+
+			line := note.Line + 1
+			i, ok := byLine[line]
+			if !ok {
+				i = len(allocations)
+				byLine[line] = i
+				allocations = append(allocations, Allocation{
+					Key:   key,
+					Path:  file.Path,
+					Line:  line,
+					Bytes: index.Allocated[key][line],
+
+					Weight: weight,
+				})
+			}
+			allocations[i].Messages = append(allocations[i].Messages, string(note.Message))
+		}
+	}
+
+	sort.Slice(allocations, func(i, k int) bool {
+		a, b := &allocations[i], &allocations[k]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+	return allocations
+}
+
+func containsAny(s string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(s, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProfileSample is a sample value attributed to the source line
+// where it was recorded.
+type ProfileSample struct {
+	File  string
+	Line  int
+	Value int64
+}
+
+// ParseProfile decodes an uncompressed pprof profile and returns the values
+// of sampleType attributed to the innermost frame of each sample.
+// When the profile has no such sample type the last one is used.
+func ParseProfile(data []byte, sampleType string) ([]ProfileSample, error) {
+	type line struct{ function, line uint64 }
+	type function struct{ name, filename uint64 }
+
+	var (
+		sampleTypes []uint64
+		samples     [][2][]uint64 // location ids and values
+		locations   = make(map[uint64]line)
+		functions   = make(map[uint64]function)
+		strs        []string
+	)
+
+	err := protoFields(data, func(field int, value uint64, payload []byte) error {
+		switch field {
+		case 1: // sample_type
+			return protoFields(payload, func(field int, value uint64, _ []byte) error {
+				if field == 1 {
+					sampleTypes = append(sampleTypes, value)
+				}
+				return nil
+			})
+		case 2: // sample
+			var sample [2][]uint64
+			err := protoFields(payload, func(field int, value uint64, packed []byte) error {
+				if field != 1 && field != 2 {
+					return nil
+				}
+				if packed == nil {
+					sample[field-1] = append(sample[field-1], value)
+					return nil
+				}
+				values, err := protoVarints(packed)
+				sample[field-1] = append(sample[field-1], values...)
+				return err
+			})
+			samples = append(samples, sample)
+			return err
+		case 4: // location
+			var id uint64
+			var first *line
+			err := protoFields(payload, func(field int, value uint64, payload []byte) error {
+				switch field {
+				case 1:
+					id = value
+				case 4:
+					if first != nil {
+						return nil
+					}
+					first = &line{}
+					return protoFields(payload, func(field int, value uint64, _ []byte) error {
+						switch field {
+						case 1:
+							first.function = value
+						case 2:
+							first.line = value
+						}
+						return nil
+					})
+				}
+				return nil
+			})
+			if first != nil {
+				locations[id] = *first
+			}
+			return err
+		case 5: // function
+			var id uint64
+			var fn function
+			err := protoFields(payload, func(field int, value uint64, _ []byte) error {
+				switch field {
+				case 1:
+					id = value
+				case 2:
+					fn.name = value
+				case 4:
+					fn.filename = value
+				}
+				return nil
+			})
+			functions[id] = fn
+			return err
+		case 6: // string_table
+			strs = append(strs, string(payload))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(sampleTypes) == 0 {
+		return nil, errors.New("profile has no sample types")
+	}
+
+	valueIndex := len(sampleTypes) - 1
+	for i, t := range sampleTypes {
+		if int(t) < len(strs) && strs[t] == sampleType {
+			valueIndex = i
+		}
+	}
+
+	result := []ProfileSample{}
+	for _, sample := range samples {
+		ids, values := sample[0], sample[1]
+		if len(ids) == 0 || valueIndex >= len(values) {
+			continue
+		}
+		loc, ok := locations[ids[0]]
+		if !ok {
+			continue
+		}
+		fn, ok := functions[loc.function]
+		if !ok || int(fn.filename) >= len(strs) {
+			continue
+		}
+		result = append(result, ProfileSample{
+			File:  strs[fn.filename],
+			Line:  int(loc.line),
+			Value: int64(values[valueIndex]),
+		})
+	}
+	return result, nil
+}
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// protoFields calls fn for every field in a protobuf message, value is set
+// for varint fields and payload for length-delimited fields.
+func protoFields(data []byte, fn func(field int, value uint64, payload []byte) error) error {
+	for len(data) > 0 {
+		key, n := protoVarint(data)
+		if n <= 0 {
+			return errTruncated
+		}
+		data = data[n:]
+
+		field, wire := int(key>>3), key&7
+		var value uint64
+		var payload []byte
+		switch wire {
+		case 0: // varint
+			value, n = protoVarint(data)
+			if n <= 0 {
+				return errTruncated
+			}
+			data = data[n:]
+		case 1: // 64-bit
+			if len(data) < 8 {
+				return errTruncated
+			}
+			data = data[8:]
+			continue
+		case 2: // length-delimited
+			size, n := protoVarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return errTruncated
+			}
+			payload = data[n : n+int(size)]
+			data = data[n+int(size):]
+		case 5: // 32-bit
+			if len(data) < 4 {
+				return errTruncated
+			}
+			data = data[4:]
+			continue
+		default:
+			return errors.New("unsupported protobuf wire type")
+		}
+
+		if err := fn(field, value, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// protoVarints decodes a packed repeated varint field.
+func protoVarints(data []byte) ([]uint64, error) {
+	var values []uint64
+	for len(data) > 0 {
+		value, n := protoVarint(data)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		values = append(values, value)
+		data = data[n:]
+	}
+	return values, nil
+}
+
+func protoVarint(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		value |= uint64(data[i]&0x7f) << (7 * uint(i))
+		if data[i] < 0x80 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
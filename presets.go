@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Preset is a combination of compiler flags for one kind of analysis,
+// so that users don't have to get the quoting of -gcflags right.
+type Preset struct {
+	Name        string
+	Gcflags     string
+	Description string
+}
+
+// presets are the values of -preset.
+var presets = []Preset{
+	{"escape", "all=-m", "escape analysis and inlining decisions"},
+	{"inline", "all=-m=2", "inlining decisions with the cost and the reason a function cannot be inlined"},
+	{"bce", "all=-d=ssa/check_bce/debug=1", "bounds checks left in the code"},
+	{"all", "all=-m=2 -d=ssa/check_bce/debug=1", "all of the above"},
+}
+
+// FindPreset returns the preset with name.
+func FindPreset(name string) (Preset, error) {
+	var names []string
+	for _, preset := range presets {
+		if preset.Name == name {
+			return preset, nil
+		}
+		names = append(names, preset.Name)
+	}
+	return Preset{}, fmt.Errorf("unknown preset %q, expected %s", name, strings.Join(names, ", "))
+}
+
+// BuildCommand returns the shell command building all packages in the
+// current directory with the flags of the preset, discarding the binaries.
+// Double quotes work both in sh and in cmd.
+func (preset Preset) BuildCommand() string {
+	return fmt.Sprintf(`go build -o %s "-gcflags=%s" ./... 2>&1`, os.DevNull, preset.Gcflags)
+}
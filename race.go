@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Race is a data race report from the race detector.
+type Race struct {
+	ID       int
+	Sections []*RaceSection
+}
+
+// RaceSection is a single access or goroutine creation in a race report,
+// e.g. "Write at 0x00c0000a0010 by goroutine 7:".
+type RaceSection struct {
+	Title  string
+	Frames []Frame
+}
+
+func init() { RegisterParser(10, raceParser{}) }
+
+type raceParser struct{}
+
+func (raceParser) Detect(line []byte) bool { return IsRaceStart(line) }
+
+func (raceParser) Parse(index *Index, dir string, lines [][]byte, at int) int {
+	return index.AddRace(dir, lines, at)
+}
+
+var (
+	raceStart     = []byte("WARNING: DATA RACE")
+	raceSeparator = []byte("==================")
+)
+
+func IsRaceStart(line []byte) bool {
+	return bytes.Equal(bytes.TrimSpace(line), raceStart)
+}
+
+// AddRace parses the race report starting at lines[at] and annotates
+// all of its frames, returns the index of the last line of the report.
+func (index *Index) AddRace(dir string, lines [][]byte, at int) int {
+	race := &Race{ID: len(index.Races) + 1}
+	index.Races = append(index.Races, race)
+
+	var section *RaceSection
+	var fn []byte
+	i := at + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := bytes.TrimSpace(line)
+		if bytes.Equal(trimmed, raceSeparator) {
+			break
+		}
+		if len(trimmed) == 0 {
+			continue
+		}
+
+		// section titles are not indented
+		if line[0] != ' ' && line[0] != '\t' {
+			section = &RaceSection{Title: string(bytes.TrimSuffix(trimmed, []byte(":")))}
+			race.Sections = append(race.Sections, section)
+			continue
+		}
+		if section == nil {
+			continue
+		}
+
+		path, lineno, ok := ParseFrameLine(trimmed)
+		if !ok {
+			fn = TrimCallArgs(trimmed)
+			continue
+		}
+
+		key, file := index.File(dir, string(path))
+		frame := Frame{
+			Func: string(fn),
+			Key:  key,
+			Path: file.Path,
+			Line: lineno,
+		}
+		section.Frames = append(section.Frames, frame)
+
+		msg := fmt.Sprintf("data race #%d: %s in %s", race.ID, section.Title, frame.Func)
+		if file.AddNote("race", lineno-1, -1, []byte(msg)) {
+			file.Stats.Add([]byte(msg))
+		}
+	}
+	return i
+}
@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestRaceParser(t *testing.T) {
+	checkParser(t, raceParser{}, `
+WARNING: DATA RACE
+Write at 0x00c0000a0010 by goroutine 7:
+  main.inc()
+      /src/main.go:10 +0x3a
+
+Previous read at 0x00c0000a0010 by goroutine 6:
+  main.get()
+      /src/main.go:15 +0x2e
+==================`, []string{
+		"main.go:10:0: race: data race #1: Write at 0x00c0000a0010 by goroutine 7 in main.inc",
+		"main.go:15:0: race: data race #1: Previous read at 0x00c0000a0010 by goroutine 6 in main.get",
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+)
+
+// Log is the data of a log as it was parsed.
+type Log struct {
+	Tool string
+	Dir  string // directory relative paths in the log are resolved against
+	Data []byte
+}
+
+// serveRawFile responds with the unmodified source of an indexed file, only
+// files in the index are served.
+func (server *Server) serveRawFile(w http.ResponseWriter, r *http.Request) {
+	info, ok := server.Index.Lookup(r.FormValue("path"))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "Not found.")
+		return
+	}
+	data, abspath, sources := info.Source, info.AbsPath, server.Index.sources()
+
+	server.unlocked(func() {
+		if data == nil {
+			ctx, cancel := server.readContext(r)
+			defer cancel()
+			var err error
+			data, err = ReadFileContext(ctx, sources, SourceName(abspath), -1)
+			if errors.Is(err, fs.ErrNotExist) {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprintf(w, "%v", err)
+				return
+			}
+			if err != nil {
+				writeLoadError(w, err)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if IsBinary(data) {
+			w.Header().Set("Content-Type", "application/octet-stream")
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filepath.Base(abspath)))
+		w.Write(data)
+	})
+}
+
+// serveRawLog responds with the logs that were parsed, concatenated in the
+// order of parsing, only those of ?tool= when given. Logs may mention files
+// outside of the shared directory, so they aren't available when sharing.
+func (server *Server) serveRawLog(w http.ResponseWriter, r *http.Request) {
+	if server.ShareRoot != "" {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, "Logs are not shared.")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	tool := r.FormValue("tool")
+	for _, log := range server.Index.Logs {
+		if tool != "" && log.Tool != tool {
+			continue
+		}
+		if _, err := w.Write(log.Data); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/fs"
+)
+
+// readChunk is how much of a file is read before checking for cancellation.
+const readChunk = 1 << 20
+
+// ReadFileContext reads at most max bytes of the file name in fsys, all of
+// it when max is negative. It returns early with the error of ctx when it is
+// done, e.g. when the client went away or a read from a slow network file
+// system timed out.
+func ReadFileContext(ctx context.Context, fsys fs.FS, name string, max int64) ([]byte, error) {
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := readFileChunked(ctx, fsys, name, max)
+		done <- result{data, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.data, r.err
+	case <-ctx.Done():
+		// a blocked read can't be interrupted, it finishes in the background
+		return nil, ctx.Err()
+	}
+}
+
+func readFileChunked(ctx context.Context, fsys fs.FS, name string, max int64) ([]byte, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rd io.Reader = file
+	size := int64(0)
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	if max >= 0 {
+		rd = io.LimitReader(file, max)
+		if size > max {
+			size = max
+		}
+	}
+
+	data := make([]byte, 0, size)
+	buf := make([]byte, readChunk)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, err := rd.Read(buf)
+		data = append(data, buf[:n]...)
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// Reindex replaces the index with the one returned by load every
+// ReindexEvery, e.g. to pick up new commits in a long-running instance.
+func (server *Server) Reindex(load func() (*Index, error)) {
+	ticker := time.NewTicker(server.ReindexEvery)
+	defer ticker.Stop()
+	for range ticker.C {
+		index, err := load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "reindex: %v\n", err)
+			if index != nil {
+				index.Close()
+			}
+			continue
+		}
+
+		server.replaceIndex(index)
+	}
+}
+
+// replaceIndex serves index instead of the current one and notifies the
+// clients watching for updates.
+func (server *Server) replaceIndex(index *Index) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	// generations keep increasing, so that clients notice the change
+	index.Generation += server.Index.Generation
+	server.Index.Close()
+	server.Index = index
+	server.notify()
+}
+
+// BuildOutput runs command with the shell in dir and returns its output.
+// A failing build is not an error, its errors are annotated instead.
+func BuildOutput(dir, command string) ([]byte, error) {
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/c", command)
+	}
+	cmd.Dir = dir
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if _, failed := err.(*exec.ExitError); !failed {
+			return nil, fmt.Errorf("%v: %v", command, err)
+		}
+	}
+	return output.Bytes(), nil
+}
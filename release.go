@@ -0,0 +1,133 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReleaseBundle is a versioned archive of the report of a release, which
+// release pipelines attach to the tag.
+type ReleaseBundle struct {
+	Version string
+	Output  string
+	Inputs  []string
+	// Time stamps the report and the files in the archive, so that the same
+	// logs of the same commit give the same archive.
+	Time time.Time
+}
+
+// ParseReleaseBundle parses the arguments of the release-bundle subcommand.
+// The version defaults to git describe, the time to $SOURCE_DATE_EPOCH or
+// the time of the commit checked out in dir.
+func ParseReleaseBundle(args []string, dir string) (*ReleaseBundle, error) {
+	set := flag.NewFlagSet("release-bundle", flag.ExitOnError)
+	release := set.String("version", "", "version of the release, git describe --tags by default")
+	output := set.String("o", "", "archive to write, view-annotated-file-report-VERSION.tar.gz by default")
+	set.Parse(args)
+
+	bundle := &ReleaseBundle{Version: *release, Output: *output, Inputs: set.Args()}
+	if bundle.Version == "" {
+		bundle.Version, _ = gitOutput(dir, "describe", "--tags", "--always")
+	}
+	if bundle.Version == "" {
+		return nil, errors.New("release-bundle: -version must be specified outside of a git repository")
+	}
+	if bundle.Output == "" {
+		bundle.Output = "view-annotated-file-report-" + bundle.Version + ".tar.gz"
+	}
+
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		epoch, _ = gitOutput(dir, "log", "-1", "--format=%ct")
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("release-bundle: set SOURCE_DATE_EPOCH outside of a git repository")
+	}
+	bundle.Time = time.Unix(seconds, 0).UTC()
+	return bundle, nil
+}
+
+// Write writes the archive of the report of the server, with the files
+// under a directory named like the archive:
+//
+//	report.html      the self-contained report, see -format html-single
+//	annotations.csv  all annotations, see -format csv
+//	snapshot.json    the metrics, see -format snapshot
+//	metadata.json    the build metadata and the version of view-annotated-file
+func (bundle *ReleaseBundle) Write(server *Server, sources bool) error {
+	index := server.Index
+	index.Metadata.Release = bundle.Version
+	index.Metadata.Time = bundle.Time
+	index.Modified = bundle.Time
+
+	var report, annotations bytes.Buffer
+	if err := server.ExportHTML(&report, sources); err != nil {
+		return err
+	}
+	if err := index.WriteTable(&annotations, ','); err != nil {
+		return err
+	}
+	snapshot, err := json.MarshalIndent(index.Snapshot(), "", "\t")
+	if err != nil {
+		return err
+	}
+	metadata, err := json.MarshalIndent(struct {
+		Metadata
+		Tool string `json:"tool"`
+	}{index.Metadata, "view-annotated-file " + Version()}, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(bundle.Output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// the gzip header has no name and time
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	prefix := strings.TrimSuffix(filepath.Base(bundle.Output), ".tar.gz") + "/"
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{"annotations.csv", annotations.Bytes()},
+		{"metadata.json", append(metadata, '\n')},
+		{"report.html", report.Bytes()},
+		{"snapshot.json", append(snapshot, '\n')},
+	} {
+		err := tw.WriteHeader(&tar.Header{
+			Name:    prefix + entry.name,
+			Mode:    0o644,
+			Size:    int64(len(entry.data)),
+			ModTime: bundle.Time,
+			Format:  tar.FormatPAX,
+		})
+		if err == nil {
+			_, err = tw.Write(entry.data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// reviewTool is the tool of the notes of review comments.
+const reviewTool = "review"
+
+// ReviewComment is a comment of a code review on a line of a file, the path
+// is relative to the root of the repository.
+type ReviewComment struct {
+	Path   string
+	Line   int // 1 is the first line
+	Author string
+	Body   string
+}
+
+var (
+	githubPullURL   = regexp.MustCompile(`^https?://([^/]+)/([^/]+/[^/]+)/pull/(\d+)`)
+	gerritChangeURL = regexp.MustCompile(`^(https?://.+?)/(?:c/.+/\+/|#/c/)?(\d+)(?:/.*)?$`)
+)
+
+// FetchReviewComments fetches the comments on lines of a GitHub pull request
+// or a Gerrit change given by its URL, e.g.
+// https://github.com/owner/name/pull/123 or
+// https://gerrit.example.com/c/project/+/4567. Returns the name of the
+// review system, used as the category of the notes.
+func FetchReviewComments(review string) (string, []ReviewComment, error) {
+	if match := githubPullURL.FindStringSubmatch(review); match != nil {
+		api := os.Getenv("GITHUB_API_URL")
+		if api == "" {
+			api = "https://api.github.com"
+			if match[1] != "github.com" {
+				api = "https://" + match[1] + "/api/v3"
+			}
+		}
+		comments, err := githubReviewComments(api, match[2], match[3], os.Getenv("GITHUB_TOKEN"))
+		return "github", comments, err
+	}
+	if match := gerritChangeURL.FindStringSubmatch(review); match != nil {
+		comments, err := gerritReviewComments(match[1], match[2], os.Getenv("GERRIT_USER"), os.Getenv("GERRIT_PASSWORD"))
+		return "gerrit", comments, err
+	}
+	return "", nil, fmt.Errorf("unknown review %q, expected the URL of a GitHub pull request or a Gerrit change", review)
+}
+
+// githubReviewComments returns the review comments of a pull request,
+// outdated ones at the line they were made on.
+func githubReviewComments(api, repo, pull, token string) ([]ReviewComment, error) {
+	var comments []ReviewComment
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/repos/%s/pulls/%s/comments?per_page=100&page=%d", api, repo, pull, page)
+		data, err := githubGet(url, token)
+		if err != nil {
+			return nil, err
+		}
+		var list []struct {
+			Path         string `json:"path"`
+			Line         *int   `json:"line"`
+			OriginalLine *int   `json:"original_line"`
+			Body         string `json:"body"`
+			User         struct {
+				Login string `json:"login"`
+			} `json:"user"`
+		}
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, err
+		}
+		for _, comment := range list {
+			line := comment.Line
+			if line == nil {
+				line = comment.OriginalLine
+			}
+			if line == nil {
+				// comments on whole files
+				continue
+			}
+			comments = append(comments, ReviewComment{
+				Path:   comment.Path,
+				Line:   *line,
+				Author: comment.User.Login,
+				Body:   comment.Body,
+			})
+		}
+		if len(list) < 100 {
+			return comments, nil
+		}
+	}
+}
+
+// gerritReviewComments returns the published comments of all patch sets of
+// a change, authenticated with the HTTP password of user when it is set.
+func gerritReviewComments(host, change, user, password string) ([]ReviewComment, error) {
+	data, err := gerritRequest(http.MethodGet, host, "/changes/"+url.PathEscape(change)+"/comments", user, password, nil)
+	if err != nil {
+		return nil, err
+	}
+	var byPath map[string][]struct {
+		Line    int    `json:"line"`
+		Message string `json:"message"`
+		Author  struct {
+			Name     string `json:"name"`
+			Username string `json:"username"`
+		} `json:"author"`
+	}
+	if err := json.Unmarshal(data, &byPath); err != nil {
+		return nil, err
+	}
+
+	var comments []ReviewComment
+	for path, list := range byPath {
+		// the commit message and patch set level comments aren't in a file
+		if strings.HasPrefix(path, "/") {
+			continue
+		}
+		for _, comment := range list {
+			if comment.Line == 0 {
+				continue
+			}
+			author := comment.Author.Username
+			if author == "" {
+				author = comment.Author.Name
+			}
+			comments = append(comments, ReviewComment{
+				Path:   path,
+				Line:   comment.Line,
+				Author: author,
+				Body:   comment.Message,
+			})
+		}
+	}
+	return comments, nil
+}
+
+// AddReviewComments adds review comments as notes of the tool "review",
+// categorized by the review system. The paths are relative to the root of
+// the repository checked out in dir.
+func (index *Index) AddReviewComments(dir string, system string, comments []ReviewComment) {
+	dir = repoRoot(dir)
+	for _, comment := range comments {
+		_, file := index.File(dir, comment.Path)
+		file.AddStructuredNote(Note{
+			Line:     comment.Line - 1,
+			Column:   -1,
+			Message:  []byte(comment.Author + ": " + strings.TrimSpace(comment.Body)),
+			Tool:     reviewTool,
+			Severity: Info,
+			Category: system,
+		})
+	}
+	index.Sort()
+	index.Generation++
+	index.Modified = time.Now()
+}
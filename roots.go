@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// root returns the project root path is resolved against, the first of
+// index.Roots containing the file, or dir when there are no roots. When dir
+// is one of the roots, e.g. the log is inside a checkout, it is preferred.
+func (index *Index) root(dir string, path string) string {
+	if len(index.Roots) == 0 {
+		return dir
+	}
+	cacheKey := dir + "\x00" + path
+	if root, ok := index.roots[cacheKey]; ok {
+		return root
+	}
+	if index.roots == nil {
+		index.roots = make(map[string]string)
+	}
+
+	candidates := index.Roots
+	for _, root := range index.Roots {
+		if root == dir {
+			candidates = append([]string{dir}, index.Roots...)
+			break
+		}
+	}
+
+	root := candidates[0]
+	for _, candidate := range candidates {
+		if filepath.IsAbs(path) {
+			if isInside(candidate, path) {
+				root = candidate
+				break
+			}
+		} else if _, err := os.Stat(filepath.Join(candidate, path)); err == nil {
+			root = candidate
+			break
+		}
+	}
+	index.roots[cacheKey] = root
+	return root
+}
+
+// LogRoot returns the root containing the log file name, so that its paths
+// are resolved against the checkout it was written in, or dir otherwise.
+func (index *Index) LogRoot(dir string, name string) string {
+	abs, err := filepath.Abs(name)
+	if name == "" || err != nil {
+		return dir
+	}
+	for _, root := range index.Roots {
+		if isInside(root, abs) {
+			return root
+		}
+	}
+	return dir
+}
+
+// rootPath returns the display path of a file inside root, which is prefixed
+// by the name of the root when there are several of them.
+func (index *Index) rootPath(root string, file *File) string {
+	if len(index.Roots) < 2 || !isInside(root, file.AbsPath) {
+		return file.Path
+	}
+	return filepath.Join(filepath.Base(root), file.Path)
+}
+
+// isInside reports whether path is inside dir.
+func isInside(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
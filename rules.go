@@ -3,13 +3,87 @@ package main
 import (
 	"bytes"
 	"strconv"
+	"strings"
 )
 
+// Severity is the importance of a note.
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+var severityNames = [...]string{"info", "warning", "error"}
+
+func (severity Severity) String() string { return severityNames[severity] }
+
+func (severity Severity) MarshalText() ([]byte, error) {
+	return []byte(severity.String()), nil
+}
+
+// ParseSeverity parses a severity name, returns false for unknown names.
+func ParseSeverity(name string) (Severity, bool) {
+	for i, v := range severityNames {
+		if v == name {
+			return Severity(i), true
+		}
+	}
+	return Info, false
+}
+
+// severityRules assign severities to messages containing one of the keywords,
+// the first matching rule wins.
+var severityRules = []struct {
+	Severity Severity
+	Keywords []string
+}{
+	{Error, []string{"error: ", "fatal error: "}},
+	{Warning, []string{
+		"cannot inline", "escapes to heap", "moved to heap",
+		"Found IsInBounds", "Found IsSliceInBounds", "warning: ",
+	}},
+}
+
+// toolSeverity is the severity of notes produced by tools
+// that report only problems.
+var toolSeverity = map[string]Severity{
+	"race":  Error,
+	"panic": Error,
+	"test":  Error,
+	"vet":   Warning,
+
+	"alloclint":   Warning,
+	"errcheck":    Warning,
+	"ineffassign": Warning,
+	"revive":      Warning,
+	"staticcheck": Warning,
+}
+
+// Classify returns the severity of a note produced by tool.
+// Tools labeled "tool/category", e.g. "vet/printf", use the severity of tool.
+func Classify(tool string, msg []byte) Severity {
+	if slash := strings.IndexByte(tool, '/'); slash > 0 {
+		tool = tool[:slash]
+	}
+	if severity, ok := toolSeverity[tool]; ok {
+		return severity
+	}
+	for _, rule := range severityRules {
+		for _, keyword := range rule.Keywords {
+			if bytes.Contains(msg, []byte(keyword)) {
+				return rule.Severity
+			}
+		}
+	}
+	return Info
+}
+
 var ignoredLines = [...]string{
 	"\t",
 	"#",
 	`.   `,
-	`<autogenerated>`,
 	`typecheck`,
 	`escwalk:`,
 	`escflood:`,
@@ -24,6 +98,7 @@ var ignoredContent = [...]string{
 }
 
 type Stat struct {
+	Name string
 	Good []string
 	Bad  []string
 }
@@ -56,10 +131,55 @@ func (stats *Stats) Add(line []byte) {
 	}
 }
 
+// NoteCategory returns the category reported by the tool of note or,
+// for plain diagnostics, the name of the statistic it counts towards.
+func NoteCategory(note *Note) string {
+	if note.Category != "" {
+		return note.Category
+	}
+	return Category(note.Message)
+}
+
+// Category returns the name of the statistic msg counts towards,
+// e.g. "inlining", or "" when there is none.
+func Category(msg []byte) string {
+	for _, stat := range statSpecs {
+		for _, keyword := range append(stat.Good, stat.Bad...) {
+			if bytes.Contains(msg, []byte(keyword)) {
+				return stat.Name
+			}
+		}
+	}
+	return ""
+}
+
 const statCount = 3
 
 var statSpecs = [statCount]Stat{
-	{[]string{"can inline", "inlining call to"}, []string{"cannot inline"}},
-	{[]string{"does not escape"}, []string{"escapes to heap"}},
-	{[]string{"bounds check elided"}, []string{"Found IsInBounds", "Found IsSliceInBounds"}},
+	{"inlining", []string{"can inline", "inlining call to"}, []string{"cannot inline"}},
+	{"escapes", []string{"does not escape"}, []string{"escapes to heap"}},
+	{"bounds checks", []string{"bounds check elided"}, []string{"Found IsInBounds", "Found IsSliceInBounds"}},
+}
+
+// Report selects notes about a specific kind of problem.
+type Report struct {
+	Name     string
+	Title    string
+	Keywords []string
+}
+
+// reports are the common fixable sources of allocations.
+var reports = []Report{
+	{"closures", "Closures escaping to heap", []string{"func literal escapes to heap", "captured by a closure"}},
+	{"interfaces", "Interface conversions escaping to heap", []string{"(interface-converted)", "interface conversion"}},
+}
+
+// FindReport returns the report with name.
+func FindReport(name string) (Report, bool) {
+	for _, report := range reports {
+		if report.Name == name {
+			return report, true
+		}
+	}
+	return Report{}, false
 }
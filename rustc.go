@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+func init() { RegisterParser(100, rustcParser{}) }
+
+// rustcParser handles the JSON diagnostics of rustc --error-format=json and
+// cargo --message-format=json, where they are wrapped in compiler messages:
+//
+//	{"$message_type":"diagnostic","message":"unused variable: `x`","code":{"code":"unused_variables"},"level":"warning","spans":[...],"children":[...]}
+//	{"reason":"compiler-message","package_id":"...","message":{"$message_type":"diagnostic",...}}
+//
+// Notes are labeled "rustc" and categorized by the lint or error code.
+type rustcParser struct{}
+
+// RustDiagnostic is a diagnostic of rustc.
+type RustDiagnostic struct {
+	Message string `json:"message"`
+	Code    *struct {
+		Code string `json:"code"`
+	} `json:"code"`
+	Level    string           `json:"level"`
+	Spans    []RustSpan       `json:"spans"`
+	Children []RustDiagnostic `json:"children"`
+}
+
+// RustSpan is a source range of a rustc diagnostic, lines and columns are 1-based.
+type RustSpan struct {
+	FileName    string `json:"file_name"`
+	LineStart   int    `json:"line_start"`
+	LineEnd     int    `json:"line_end"`
+	ColumnStart int    `json:"column_start"`
+	ColumnEnd   int    `json:"column_end"`
+	IsPrimary   bool   `json:"is_primary"`
+	Label       string `json:"label"`
+}
+
+var rustSeverity = map[string]Severity{
+	"error":                          Error,
+	"error: internal compiler error": Error,
+	"warning":                        Warning,
+}
+
+func (rustcParser) Detect(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("{")) &&
+		(bytes.Contains(line, []byte(`"$message_type":"diagnostic"`)) ||
+			bytes.HasPrefix(line, []byte(`{"reason":"compiler-message"`)))
+}
+
+func (rustcParser) Parse(index *Index, dir string, lines [][]byte, at int) int {
+	var diagnostic RustDiagnostic
+	if bytes.HasPrefix(lines[at], []byte(`{"reason":`)) {
+		var message struct {
+			Message RustDiagnostic `json:"message"`
+		}
+		if err := json.Unmarshal(lines[at], &message); err != nil {
+			return at
+		}
+		diagnostic = message.Message
+	} else if err := json.Unmarshal(lines[at], &diagnostic); err != nil {
+		return at
+	}
+
+	category := ""
+	if diagnostic.Code != nil {
+		category = diagnostic.Code.Code
+	}
+	index.addRustDiagnostic(dir, &diagnostic, category)
+	for i := range diagnostic.Children {
+		index.addRustDiagnostic(dir, &diagnostic.Children[i], category)
+	}
+	return at
+}
+
+// addRustDiagnostic adds a note for each span of diagnostic, secondary spans
+// are added with their labels as notes.
+func (index *Index) addRustDiagnostic(dir string, diagnostic *RustDiagnostic, category string) {
+	for _, span := range diagnostic.Spans {
+		msg := diagnostic.Level + ": " + diagnostic.Message
+		severity := rustSeverity[diagnostic.Level]
+		if !span.IsPrimary {
+			if span.Label == "" {
+				continue
+			}
+			msg, severity = "note: "+span.Label, Info
+		} else if span.Label != "" {
+			msg += " (" + span.Label + ")"
+		}
+
+		note := Note{
+			Line:     span.LineStart - 1,
+			Column:   span.ColumnStart - 1,
+			Message:  []byte(msg),
+			Tool:     "rustc",
+			Severity: severity,
+			Category: category,
+		}
+		if span.LineEnd == span.LineStart && span.ColumnEnd > span.ColumnStart {
+			note.Span = &Span{Start: span.ColumnStart - 1, End: span.ColumnEnd - 1}
+		}
+		_, file := index.File(dir, span.FileName)
+		file.AddStructuredNote(note)
+	}
+}
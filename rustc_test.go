@@ -0,0 +1,10 @@
+package main
+
+import "testing"
+
+func TestRustcParser(t *testing.T) {
+	checkParser(t, rustcParser{}, `
+{"$message_type":"diagnostic","message":"unused variable: `+"`x`"+`","code":{"code":"unused_variables"},"level":"warning","spans":[{"file_name":"src/main.rs","line_start":2,"line_end":2,"column_start":9,"column_end":10,"is_primary":true,"label":null}],"children":[]}`, []string{
+		"src/main.rs:2:9: rustc: warning: unused variable: `x`",
+	})
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SARIF is the subset of a SARIF 2.1.0 log read by ParseSARIF, see
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type SARIF struct {
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+type SARIFRun struct {
+	Tool struct {
+		Driver struct {
+			Name  string      `json:"name"`
+			Rules []SARIFRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	OriginalURIBaseIDs map[string]struct {
+		URI string `json:"uri"`
+	} `json:"originalUriBaseIds"`
+	Results []SARIFResult `json:"results"`
+}
+
+type SARIFRule struct {
+	ID               string `json:"id"`
+	ShortDescription struct {
+		Text string `json:"text"`
+	} `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+	MessageStrings map[string]struct {
+		Text string `json:"text"`
+	} `json:"messageStrings"`
+}
+
+type SARIFResult struct {
+	RuleID    string `json:"ruleId"`
+	RuleIndex *int   `json:"ruleIndex"`
+	Level     string `json:"level"`
+	Message   struct {
+		Text      string   `json:"text"`
+		ID        string   `json:"id"`
+		Arguments []string `json:"arguments"`
+	} `json:"message"`
+	Locations []struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI       string `json:"uri"`
+				URIBaseID string `json:"uriBaseId"`
+			} `json:"artifactLocation"`
+			Region struct {
+				StartLine   int `json:"startLine"`
+				StartColumn int `json:"startColumn"`
+				EndLine     int `json:"endLine"`
+				EndColumn   int `json:"endColumn"`
+			} `json:"region"`
+		} `json:"physicalLocation"`
+	} `json:"locations"`
+}
+
+var sarifSeverity = map[string]Severity{
+	"error":   Error,
+	"warning": Warning,
+	"note":    Info,
+	"none":    Info,
+}
+
+func init() { RegisterInputParser(160, sarifParser{}) }
+
+type sarifParser struct{}
+
+func (sarifParser) DetectInput(data []byte) bool { return IsSARIF(data) }
+
+func (sarifParser) ParseInput(index *Index, dir string, data []byte) error {
+	return index.ParseSARIF(dir, data)
+}
+
+// IsSARIF reports whether data is a SARIF log, e.g. of CodeQL, gosec or
+// semgrep.
+func IsSARIF(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	if !bytes.HasPrefix(data, []byte("{")) {
+		return false
+	}
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	return bytes.Contains(head, []byte("sarif")) && bytes.Contains(data, []byte(`"runs"`))
+}
+
+// ParseSARIF adds the results of a SARIF log to the index. Notes are labeled
+// with the lower case name of the tool, e.g. "gosec", and categorized by
+// rule. Results without a location in a file are skipped.
+func (index *Index) ParseSARIF(dir string, data []byte) error {
+	var log SARIF
+	if err := json.Unmarshal(data, &log); err != nil {
+		return err
+	}
+	for _, run := range log.Runs {
+		tool := strings.ToLower(strings.Fields(run.Tool.Driver.Name + " sarif")[0])
+		rules := make(map[string]*SARIFRule)
+		for i := range run.Tool.Driver.Rules {
+			rules[run.Tool.Driver.Rules[i].ID] = &run.Tool.Driver.Rules[i]
+		}
+
+		for _, result := range run.Results {
+			rule := rules[result.RuleID]
+			if result.RuleIndex != nil && *result.RuleIndex >= 0 && *result.RuleIndex < len(run.Tool.Driver.Rules) {
+				rule = &run.Tool.Driver.Rules[*result.RuleIndex]
+			}
+			level := result.Level
+			if level == "" && rule != nil {
+				level = rule.DefaultConfiguration.Level
+			}
+			severity, ok := sarifSeverity[level]
+			if !ok {
+				severity = Warning
+			}
+			category := result.RuleID
+			if category == "" && rule != nil {
+				category = rule.ID
+			}
+
+			for _, location := range result.Locations {
+				artifact := location.PhysicalLocation.ArtifactLocation
+				region := location.PhysicalLocation.Region
+				if artifact.URI == "" || region.StartLine < 1 {
+					continue
+				}
+				base := ""
+				if artifact.URIBaseID != "" {
+					base = run.OriginalURIBaseIDs[artifact.URIBaseID].URI
+				}
+				note := Note{
+					Line:     region.StartLine - 1,
+					Column:   region.StartColumn - 1,
+					Message:  []byte(sarifMessage(result, rule)),
+					Tool:     tool,
+					Severity: severity,
+					Category: category,
+				}
+				if region.StartColumn > 0 && region.EndColumn > region.StartColumn && (region.EndLine == 0 || region.EndLine == region.StartLine) {
+					note.Span = &Span{Start: region.StartColumn - 1, End: region.EndColumn - 1}
+				}
+				_, file := index.File(dir, sarifPath(base, artifact.URI))
+				file.AddStructuredNote(note)
+			}
+		}
+	}
+	return nil
+}
+
+// sarifMessage returns the text of the message of a result, which can also
+// be a message string of its rule with {0} placeholders for arguments.
+func sarifMessage(result SARIFResult, rule *SARIFRule) string {
+	text := result.Message.Text
+	if text == "" && rule != nil {
+		text = rule.MessageStrings[result.Message.ID].Text
+		if text == "" {
+			text = rule.ShortDescription.Text
+		}
+	}
+	for i, argument := range result.Message.Arguments {
+		text = strings.Replace(text, "{"+strconv.Itoa(i)+"}", argument, -1)
+	}
+	return text
+}
+
+// sarifPath returns the file path of a URI, relative ones are resolved
+// against the URI of their base, e.g. file:///src/ for %SRCROOT%.
+func sarifPath(base, uri string) string {
+	if !strings.Contains(uri, ":") && base != "" {
+		uri = strings.TrimSuffix(base, "/") + "/" + uri
+	}
+	if parsed, err := url.Parse(uri); err == nil && (parsed.Scheme == "file" || parsed.Scheme == "") {
+		uri = parsed.Path
+		// file:///C:/src on Windows
+		if parsed.Scheme == "file" && len(uri) > 2 && uri[0] == '/' && uri[2] == ':' {
+			uri = uri[1:]
+		}
+	}
+	return filepath.FromSlash(uri)
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestSARIFParser(t *testing.T) {
+	checkInputParser(t, sarifParser{}, []byte(`{
+	"version": "2.1.0",
+	"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+	"runs": [{
+		"tool": {"driver": {"name": "gosec", "rules": [
+			{"id": "G104", "defaultConfiguration": {"level": "warning"}}
+		]}},
+		"originalUriBaseIds": {"SRCROOT": {"uri": "file:///src/"}},
+		"results": [{
+			"ruleId": "G104",
+			"message": {"text": "Errors unhandled."},
+			"locations": [{"physicalLocation": {
+				"artifactLocation": {"uri": "pkg/foo.go", "uriBaseId": "SRCROOT"},
+				"region": {"startLine": 12, "startColumn": 2, "endColumn": 11}
+			}}]
+		}]
+	}]
+}`), []string{
+		"pkg/foo.go:12:2: gosec: Errors unhandled.",
+	})
+}
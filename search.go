@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"regexp"
+)
+
+// maxSearchMatches limits the matches of a search in a file.
+const maxSearchMatches = 1000
+
+// SearchResult are the matches of a search in the source of a file.
+type SearchResult struct {
+	Matches []SearchMatch `json:"matches"`
+	// Truncated is set when there were more than maxSearchMatches matches.
+	Truncated bool `json:"truncated"`
+}
+
+// SearchMatch is a match in a line, Start and End are byte offsets
+// like the columns of notes.
+type SearchMatch struct {
+	Line  int `json:"line"` // 1 is the first line
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Search finds the matches of re in the source of path, as shown by
+// LoadAnnotatedFile, so that the browser needn't load huge files to search.
+func (index *Index) Search(ctx context.Context, path string, re *regexp.Regexp) (*SearchResult, error) {
+	snapshot, ok := index.snapshotFile(path, Filter{})
+	if !ok {
+		return nil, errNotFound
+	}
+	return snapshot.Search(ctx, re)
+}
+
+// Search finds the matches of re in the source of the file, see
+// Index.Search.
+func (snapshot *fileSnapshot) Search(ctx context.Context, re *regexp.Regexp) (*SearchResult, error) {
+	data, _, err := snapshot.loadSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SearchResult{Matches: []SearchMatch{}}
+	if IsBinary(data) {
+		return result, nil
+	}
+	for i, line := range SourceLines(data) {
+		line, _ = snapshot.limits.truncateLine(line)
+		for _, match := range re.FindAllStringIndex(line, -1) {
+			if match[0] == match[1] {
+				continue
+			}
+			if len(result.Matches) == maxSearchMatches {
+				result.Truncated = true
+				return result, nil
+			}
+			result.Matches = append(result.Matches, SearchMatch{i + 1, match[0], match[1]})
+		}
+		if i%1000 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return result, nil
+}
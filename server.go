@@ -0,0 +1,877 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// openapiSpec describes the JSON API.
+//
+//go:embed openapi.json
+var openapiSpec []byte
+
+type Server struct {
+	Index    *Index
+	Template *template.Template
+
+	// CORSOrigins are origins allowed to access the API, "*" allows any.
+	CORSOrigins []string
+
+	// Live is set when logs are parsed while serving, see Follow.
+	Live bool
+
+	// ReadTimeout limits reading a file for a request, 0 is no limit.
+	ReadTimeout time.Duration
+	// Order is the default order of the file list, see OrderedFiles.
+	Order string
+	// Explanations of compiler messages, nil uses the built-in ones.
+	Explanations []Explanation
+	// Demo is set when the sample project of -demo is shown.
+	Demo bool
+	// History are the recorded runs, nil when -history isn't set.
+	History *History
+
+	// LintAllocations and ExcludeGenerated apply -lint-allocs and
+	// -exclude-generated to the logs parsed while serving, see Parse.
+	LintAllocations  bool
+	ExcludeGenerated bool
+
+	// ReindexEvery is the interval the index is rebuilt at, see Reindex.
+	ReindexEvery time.Duration
+
+	// ShareRoot and ShareToken are set in the read-only sharing mode, where
+	// requests need the token and paths are shown relative to ShareRoot.
+	ShareRoot  string
+	ShareToken string
+
+	// mu guards Index, updated is closed when the index changes.
+	mu      sync.Mutex
+	updated chan struct{}
+	// checking is held while the packages are type-checked, see
+	// lockTypeChecked.
+	checking sync.Mutex
+	// linting is held while packages are linted by linter, see Parse.
+	linting sync.Mutex
+	linter  *allocLinter
+}
+
+// Parse adds notes from data to the index and notifies clients
+// watching for updates. The new packages are linted for allocations
+// afterwards, without holding mu, as type-checking them takes long.
+func (server *Server) Parse(dir string, tool string, data []byte) {
+	server.mu.Lock()
+	server.Index.Parse(dir, tool, data)
+	var dirs []string
+	if server.LintAllocations {
+		dirs = server.Index.allocLintDirs()
+	}
+	sources := server.Index.sources()
+	server.finishParse()
+	server.mu.Unlock()
+
+	if len(dirs) == 0 {
+		return
+	}
+	server.linting.Lock()
+	defer server.linting.Unlock()
+	if server.linter == nil {
+		server.linter = newAllocLinter()
+	}
+	lints := server.linter.Lint(sources, dirs)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.Index.addAllocLints(lints)
+	server.finishParse()
+}
+
+// finishParse applies the flags to the parsed notes and notifies clients,
+// mu must be held.
+func (server *Server) finishParse() {
+	if server.ExcludeGenerated {
+		server.Index.ExcludeGenerated()
+	}
+	if server.ShareRoot != "" {
+		server.Index.Share(server.ShareRoot)
+	}
+	server.notify()
+}
+
+// notify wakes up the clients watching for updates, mu must be held.
+func (server *Server) notify() {
+	if server.updated != nil {
+		close(server.updated)
+	}
+	server.updated = make(chan struct{})
+}
+
+// IndexPage is the data model of the index page template.
+type IndexPage struct {
+	StatCount int
+	Stats     [statCount]Stat
+	Files     map[string]*File // by index key
+	// Groups are the files in Order, the default order of the file list,
+	// Changed the last commit times of the files by index key.
+	Groups  []FileGroup
+	Order   string
+	Changed map[string]time.Time
+	Dirs    []Dir
+	Races   []*Race
+	Stacks  []*Goroutine
+	Tools   []string
+	Live    bool
+
+	// Unparsed are the log lines no parser understood.
+	Unparsed UnparsedLines
+	// Logs is set when the parsed logs can be viewed at /log.
+	Logs bool
+	// Explanations are shown for the annotations they match.
+	Explanations []Explanation
+	// Demo shows a tour of the UI for the sample project.
+	Demo bool
+	// History is set when the history of lines can be loaded from
+	// /api/v1/history.
+	History bool
+
+	// Weighted are the stats weighted by package, when -weights is given.
+	Weighted *WeightedStats
+	// Owners are the stats by owner, when there is a CODEOWNERS file.
+	Owners []OwnerStats
+
+	// Indexed is when the index was built, shown when it is rebuilt periodically.
+	Indexed time.Time
+	// Metadata describes the build the logs come from.
+	Metadata Metadata
+
+	// Allocations are the top escape sites by allocated bytes,
+	// when a heap profile was loaded.
+	Allocations []Allocation
+	Reports     []ReportSites
+
+	// Embedded is the data of an exported report, which has no server.
+	Embedded *Embedded
+}
+
+// ReportSites are the sites found by a report.
+type ReportSites struct {
+	Report
+	Sites []Allocation
+}
+
+// GenerationInfo identifies the version of the index.
+type GenerationInfo struct {
+	Generation int       `json:"generation"`
+	Modified   time.Time `json:"modified"`
+}
+
+// FilePage is the data model of the file page template.
+type FilePage struct {
+	Stats [statCount]Stat
+	File  *AnnotatedFile
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if acceptsGzip(r) && !IsWebSocket(r) {
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.Close()
+		w = gw
+	}
+
+	if server.ShareToken != "" {
+		if !server.authorizeShare(w, r) {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintf(w, "Invalid share token.")
+			return
+		}
+	}
+
+	// the API is available cross-origin, the UI isn't
+	if r.URL.Path != "" && r.URL.Path != "/" {
+		if server.allowCORS(w, r) && r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	// the watch connection outlives the request and locks on its own
+	if r.URL.Path == "/api/v1/watch" {
+		server.serveWatch(w, r)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/api/v1/devirtualization", "/api/v1/escapes-by-type", "/api/v1/pool-candidates", "/api/v1/symbols":
+		server.lockTypeChecked()
+	default:
+		server.mu.Lock()
+	}
+	defer server.mu.Unlock()
+
+	if server.ShareRoot != "" {
+		// index keys are canonical paths, which may differ in case
+		roots := []string{server.ShareRoot}
+		if canonical := server.Index.CanonicalPath("", server.ShareRoot); canonical != server.ShareRoot {
+			roots = append(roots, canonical)
+		}
+		sw := &shareResponseWriter{ResponseWriter: w, roots: roots}
+		defer sw.Close()
+		w = sw
+	}
+
+	// clients can detect that the data changed and refresh
+	w.Header().Set("X-Index-Generation", strconv.Itoa(server.Index.Generation))
+
+	switch r.URL.Path {
+	case "", "/":
+		server.serveIndex(w, r)
+	case "/file":
+		server.serveFile(w, r)
+	case "/dir":
+		server.serveDir(w, r)
+	case "/view":
+		server.serveView(w, r)
+	case "/log":
+		server.serveLog(w, r)
+	case "/raw/file":
+		server.serveRawFile(w, r)
+	case "/raw/log":
+		server.serveRawLog(w, r)
+	case "/api/openapi.json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openapiSpec)
+	case "/api/v1/generation":
+		writeJSON(w, &GenerationInfo{
+			Generation: server.Index.Generation,
+			Modified:   server.Index.Modified,
+		})
+	case "/api/v1/line":
+		server.serveLine(w, r)
+	case "/api/v1/allocations":
+		writeJSON(w, server.Index.Allocations())
+	case "/api/v1/devirtualization":
+		writeJSON(w, server.Index.Devirtualization())
+	case "/api/v1/escapes-by-type":
+		writeJSON(w, server.Index.EscapesByType())
+	case "/api/v1/pool-candidates":
+		writeJSON(w, server.Index.PoolCandidates())
+	case "/api/v1/badge":
+		writeJSON(w, server.Index.HeapEscapesShield())
+	case "/snippet.svg":
+		server.serveSnippet(w, r)
+	case "/badge.svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		WriteBadge(w, badgeLabel, strconv.Itoa(server.Index.HeapEscapes()))
+	case "/api/v1/search":
+		server.serveSearch(w, r)
+	case "/api/v1/symbols":
+		server.serveSymbols(w, r)
+	case "/api/v1/owners":
+		writeJSON(w, server.Index.OwnerStats())
+	case "/api/v1/explain":
+		server.serveExplain(w, r)
+	case "/api/v1/history":
+		server.serveHistory(w, r)
+	case "/api/v1/metadata":
+		writeJSON(w, server.Index.Metadata)
+	case "/api/v1/unparsed":
+		unparsed := server.Index.Unparsed
+		if unparsed.Lines == nil {
+			unparsed.Lines = []string{}
+		}
+		writeJSON(w, unparsed)
+	case "/api/v1/inlining":
+		writeJSON(w, server.Index.InlineStatuses(r.URL.Query().Get("package")))
+	case "/api/v1/report":
+		server.serveReport(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (server *Server) serveIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := server.Template.ExecuteTemplate(w, "index.html", server.indexPage())
+	if err != nil {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+}
+
+func (server *Server) indexPage() *IndexPage {
+	return &IndexPage{
+		StatCount:    statCount,
+		Stats:        statSpecs,
+		Files:        server.Index.Files,
+		Groups:       server.Index.GroupFiles(server.order()),
+		Order:        server.order(),
+		Changed:      server.Index.ChangeTimes(),
+		Dirs:         server.Index.Dirs(),
+		Races:        server.Index.Races,
+		Stacks:       server.Index.Goroutines,
+		Tools:        server.Index.Tools(),
+		Live:         server.Live,
+		Indexed:      server.indexed(),
+		Metadata:     server.Index.Metadata,
+		Unparsed:     server.Index.Unparsed,
+		Logs:         len(server.Index.Logs) > 0 && server.ShareRoot == "",
+		Explanations: server.explanations(),
+		Demo:         server.Demo,
+		History:      server.History != nil,
+		Weighted:     server.weightedStats(),
+		Owners:       server.ownerStats(),
+
+		Allocations: server.topAllocations(20),
+		Reports:     server.reports(),
+	}
+}
+
+func (server *Server) order() string {
+	if server.Order == "" {
+		return fileOrders[0]
+	}
+	return server.Order
+}
+
+func (server *Server) ownerStats() []OwnerStats {
+	if server.Index.CodeOwners == nil {
+		return nil
+	}
+	return server.Index.OwnerStats()
+}
+
+func (server *Server) weightedStats() *WeightedStats {
+	if server.Index.Weights == nil {
+		return nil
+	}
+	stats := server.Index.WeightedStats()
+	return &stats
+}
+
+func (server *Server) indexed() time.Time {
+	if server.ReindexEvery == 0 {
+		return time.Time{}
+	}
+	return server.Index.Modified
+}
+
+func (server *Server) topAllocations(n int) []Allocation {
+	if server.Index.Allocated == nil {
+		return nil
+	}
+
+	top := []Allocation{}
+	for _, allocation := range server.Index.Allocations() {
+		if len(top) >= n || allocation.Bytes <= 0 {
+			break
+		}
+		top = append(top, allocation)
+	}
+	return top
+}
+
+func (server *Server) reports() []ReportSites {
+	result := []ReportSites{}
+	for _, report := range reports {
+		sites := server.Index.Sites(report.Keywords)
+		if len(sites) > 0 {
+			result = append(result, ReportSites{report, sites})
+		}
+	}
+	return result
+}
+
+// serveReport responds with the sites found by the named report.
+func (server *Server) serveReport(w http.ResponseWriter, r *http.Request) {
+	report, ok := FindReport(r.FormValue("name"))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "Unknown report %q.", r.FormValue("name"))
+		return
+	}
+	writeJSON(w, server.Index.Sites(report.Keywords))
+}
+
+// serveView renders a file with the user supplied file template.
+func (server *Server) serveView(w http.ResponseWriter, r *http.Request) {
+	if server.Template.Lookup("file.html") == nil {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "No file template specified.")
+		return
+	}
+
+	path := r.FormValue("path")
+	if path == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "No path specified.")
+		return
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%v", err)
+		return
+	}
+
+	snapshot, ok := server.Index.snapshotFile(path, filter)
+	if !ok {
+		writeLoadError(w, errNotFound)
+		return
+	}
+
+	server.unlocked(func() {
+		ctx, cancel := server.readContext(r)
+		defer cancel()
+		annotated, err := snapshot.Load(ctx)
+		if err != nil {
+			writeLoadError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err = server.Template.ExecuteTemplate(w, "file.html", &FilePage{
+			Stats: statSpecs,
+			File:  annotated,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	})
+}
+
+func (server *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	path := r.FormValue("path")
+	if path == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "No path specified.")
+		return
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%v", err)
+		return
+	}
+
+	offset, limit, err := parsePage(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%v", err)
+		return
+	}
+	fields, err := parseFields(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%v", err)
+		return
+	}
+
+	snapshot, ok := server.Index.snapshotFile(path, filter)
+	if !ok {
+		writeLoadError(w, errNotFound)
+		return
+	}
+
+	server.unlocked(func() {
+		if notModified(w, r, snapshot) {
+			return
+		}
+
+		ctx, cancel := server.readContext(r)
+		defer cancel()
+		annotated, err := snapshot.Load(ctx)
+		if err != nil {
+			writeLoadError(w, err)
+			return
+		}
+		annotated.Page(offset, limit)
+
+		if fields == nil {
+			writeJSON(w, annotated)
+			return
+		}
+		writeJSON(w, &struct {
+			*AnnotatedFile
+			Lines []map[string]interface{} `json:"lines"`
+		}{annotated, SelectLineFields(annotated, fields)})
+	})
+}
+
+// lineFields are the fields of a line that can be selected with ?fields=.
+var lineFields = [...]string{"line", "source", "notes"}
+
+// SelectLineFields returns the lines of file with only the given fields,
+// "line" is the line number, 1 being the first line.
+func SelectLineFields(file *AnnotatedFile, fields []string) []map[string]interface{} {
+	lines := make([]map[string]interface{}, 0, len(file.Lines))
+	for i, line := range file.Lines {
+		selected := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			switch field {
+			case "line":
+				selected[field] = file.Offset + i + 1
+			case "source":
+				selected[field] = line.Source
+				if line.Truncated > 0 {
+					selected["truncated"] = line.Truncated
+				}
+			case "notes":
+				selected[field] = line.Notes
+				if line.Omitted > 0 {
+					selected["omitted"] = line.Omitted
+				}
+			}
+		}
+		lines = append(lines, selected)
+	}
+	return lines
+}
+
+// parsePage parses ?offset=&limit=, limit is -1 when missing.
+func parsePage(r *http.Request) (offset, limit int, err error) {
+	limit = -1
+	if value := r.FormValue("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("Invalid offset %q.", value)
+		}
+	}
+	if value := r.FormValue("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return 0, 0, fmt.Errorf("Invalid limit %q.", value)
+		}
+	}
+	return offset, limit, nil
+}
+
+// parseFields parses ?fields=line,notes, returns nil when missing.
+func parseFields(r *http.Request) ([]string, error) {
+	value := r.FormValue("fields")
+	if value == "" {
+		return nil, nil
+	}
+	fields := strings.Split(value, ",")
+	for _, field := range fields {
+		known := false
+		for _, name := range lineFields {
+			known = known || field == name
+		}
+		if !known {
+			return nil, fmt.Errorf("Unknown field %q.", field)
+		}
+	}
+	return fields, nil
+}
+
+// serveDir responds with all files directly inside a directory.
+func (server *Server) serveDir(w http.ResponseWriter, r *http.Request) {
+	path := r.FormValue("path")
+	if path == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "No path specified.")
+		return
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%v", err)
+		return
+	}
+
+	if server.ShareRoot != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(server.ShareRoot, path)
+	}
+
+	snapshots, ok := server.Index.snapshotDir(path, filter)
+	if !ok {
+		writeLoadError(w, errNotFound)
+		return
+	}
+
+	server.unlocked(func() {
+		ctx, cancel := server.readContext(r)
+		defer cancel()
+		annotated, err := loadAnnotatedDir(ctx, path, snapshots)
+		if err != nil {
+			writeLoadError(w, err)
+			return
+		}
+
+		writeJSON(w, annotated)
+	})
+}
+
+// unlocked runs fn without holding mu, which ServeHTTP holds while calling
+// the handlers, so that reading sources from a slow file system doesn't
+// block other requests. fn must only use data copied from the index.
+func (server *Server) unlocked(fn func()) {
+	server.mu.Unlock()
+	defer server.mu.Lock()
+	fn()
+}
+
+// readContext returns the context for reading files in response to r,
+// which is done when the client goes away or after ReadTimeout.
+func (server *Server) readContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if server.ReadTimeout <= 0 {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), server.ReadTimeout)
+}
+
+// writeLoadError responds with an error of loading files.
+func writeLoadError(w http.ResponseWriter, err error) {
+	switch err {
+	case context.Canceled:
+		// the client went away
+	case context.DeadlineExceeded:
+		w.WriteHeader(http.StatusGatewayTimeout)
+		fmt.Fprintf(w, "Reading the file timed out.")
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		fmt.Fprintf(w, "Error: %v", err)
+	}
+}
+
+// serveSearch responds with the matches of ?q= in the source of a file.
+func (server *Server) serveSearch(w http.ResponseWriter, r *http.Request) {
+	path := r.FormValue("path")
+	if path == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "No path specified.")
+		return
+	}
+	re, err := regexp.Compile(r.FormValue("q"))
+	if err != nil || r.FormValue("q") == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid regexp %q.", r.FormValue("q"))
+		return
+	}
+
+	snapshot, ok := server.Index.snapshotFile(path, Filter{})
+	if !ok {
+		writeLoadError(w, errNotFound)
+		return
+	}
+
+	server.unlocked(func() {
+		ctx, cancel := server.readContext(r)
+		defer cancel()
+		result, err := snapshot.Search(ctx, re)
+		if err != nil {
+			writeLoadError(w, err)
+			return
+		}
+		writeJSON(w, result)
+	})
+}
+
+// serveLine responds with the notes of a single line and its surrounding
+// source, for clients that don't need the whole file.
+func (server *Server) serveLine(w http.ResponseWriter, r *http.Request) {
+	server.lineContext(w, r, func(info *LineInfo) {
+		writeJSON(w, info)
+	})
+}
+
+// serveSnippet responds with an SVG image of a line and its surrounding
+// source with the notes, for chats and issue trackers that don't show HTML.
+func (server *Server) serveSnippet(w http.ResponseWriter, r *http.Request) {
+	server.lineContext(w, r, func(info *LineInfo) {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		WriteSnippet(w, info)
+	})
+}
+
+// lineContext loads the line requested with ?path=&line=&context= and calls
+// respond with it without holding mu, unless it has responded with an error
+// or 304 Not Modified.
+func (server *Server) lineContext(w http.ResponseWriter, r *http.Request, respond func(*LineInfo)) {
+	path := r.FormValue("path")
+	if path == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "No path specified.")
+		return
+	}
+
+	line, err := strconv.Atoi(r.FormValue("line"))
+	if err != nil || line < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid line %q.", r.FormValue("line"))
+		return
+	}
+
+	context := 3
+	if value := r.FormValue("context"); value != "" {
+		context, err = strconv.Atoi(value)
+		if err != nil || context < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Invalid context %q.", value)
+			return
+		}
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%v", err)
+		return
+	}
+
+	snapshot, ok := server.Index.snapshotFile(path, filter)
+	if !ok {
+		writeLoadError(w, errNotFound)
+		return
+	}
+
+	server.unlocked(func() {
+		if notModified(w, r, snapshot) {
+			return
+		}
+
+		ctx, cancel := server.readContext(r)
+		defer cancel()
+		annotated, err := snapshot.Load(ctx)
+		if err != nil {
+			writeLoadError(w, err)
+			return
+		}
+
+		info, ok := annotated.LineContext(line, context)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "Line %v is outside of %v.", line, annotated.Path)
+			return
+		}
+		respond(info)
+	})
+}
+
+// allowCORS adds the CORS headers when the request origin is allowed,
+// returns false when the request is not a cross-origin request or the
+// origin is not allowed.
+func (server *Server) allowCORS(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || len(server.CORSOrigins) == 0 {
+		return false
+	}
+	w.Header().Add("Vary", "Origin")
+
+	for _, allowed := range server.CORSOrigins {
+		if allowed == "*" || allowed == origin {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified")
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "If-None-Match, If-Modified-Since")
+				w.Header().Set("Access-Control-Max-Age", "86400")
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// allowedOrigin reports whether the origin of r, which browsers send with
+// WebSocket requests, is the host of the server or one of CORSOrigins.
+// Requests without an origin are not from browsers and are allowed.
+func (server *Server) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range server.CORSOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets the caching headers for responses derived from the file
+// and reports whether the client already has the current version, in which
+// case it has responded with 304 Not Modified.
+func notModified(w http.ResponseWriter, r *http.Request, snapshot *fileSnapshot) bool {
+	stat, err := fs.Stat(snapshot.sources, SourceName(snapshot.absPath))
+	if err != nil {
+		return false
+	}
+
+	modified := stat.ModTime()
+	if snapshot.modified.After(modified) {
+		modified = snapshot.modified
+	}
+
+	query := fnv.New64a()
+	query.Write([]byte(r.URL.RawQuery))
+	etag := fmt.Sprintf(`W/"%d-%x-%x"`, snapshot.generation, stat.ModTime().UnixNano(), query.Sum64())
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		if match != etag {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		if err != nil || modified.Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// parseFilter parses the "tool" and "severity" query parameters.
+func parseFilter(r *http.Request) (Filter, error) {
+	filter := Filter{Tool: r.FormValue("tool")}
+	switch r.FormValue("positions") {
+	case "", "original":
+	case "generated":
+		filter.GeneratedPositions = true
+	default:
+		return filter, fmt.Errorf("Unknown positions %q.", r.FormValue("positions"))
+	}
+	if severity := r.FormValue("severity"); severity != "" {
+		var ok bool
+		filter.Severity, ok = ParseSeverity(severity)
+		if !ok {
+			return filter, fmt.Errorf("Unknown severity %q.", severity)
+		}
+	}
+	return filter, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// blockingFS is a file system whose files can't be opened until unblocked,
+// like a hanging network file system.
+type blockingFS struct {
+	files   fstest.MapFS
+	opening chan string
+	unblock chan struct{}
+}
+
+func (fsys blockingFS) Open(name string) (fs.File, error) {
+	fsys.opening <- name
+	<-fsys.unblock
+	return fsys.files.Open(name)
+}
+
+// TestSlowSource checks that requests are served while the source of a
+// file is being read.
+func TestSlowSource(t *testing.T) {
+	sources := blockingFS{
+		files:   fstest.MapFS{"src/main.go": {Data: []byte("package main\n")}},
+		opening: make(chan string, 100),
+		unblock: make(chan struct{}),
+	}
+	index := NewIndex()
+	index.Sources = fstest.MapFS{}
+	index.Parse("/src", "", []byte("main.go:1:1: can inline main\n"))
+	index.Sources = sources
+	server := &Server{Index: index}
+
+	loaded := make(chan int)
+	for _, path := range []string{"/file", "/api/v1/search", "/api/v1/line", "/raw/file"} {
+		path := path + "?" + url.Values{"path": {"main.go"}, "q": {"main"}, "line": {"1"}}.Encode()
+		go func() {
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			loaded <- w.Code
+		}()
+	}
+
+	// all of them read at the same time, while other requests are served
+	for i := 0; i < 4; i++ {
+		select {
+		case <-sources.opening:
+		case <-time.After(5 * time.Second):
+			t.Fatal("loading files blocked by reading a source")
+		}
+	}
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/generation", nil))
+		done <- w.Code
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request blocked by reading a source")
+	}
+
+	close(sources.unblock)
+	for i := 0; i < 4; i++ {
+		if code := <-loaded; code != http.StatusOK {
+			t.Errorf("loading the file responded with %v", code)
+		}
+	}
+}
+
+// TestParseLintAllocations checks that packages of logs parsed while serving
+// are linted for allocations.
+func TestParseLintAllocations(t *testing.T) {
+	dir := t.TempDir()
+	src := "package demo\n\nfunc Join(names []string) (s string) {\n\tfor _, name := range names {\n\t\ts += name\n\t}\n\treturn s\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "demo.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := &Server{Index: NewIndex(), LintAllocations: true}
+	server.Parse(dir, "", []byte("demo.go:3:6: can inline Join\n"))
+
+	_, file := server.Index.File(dir, "demo.go")
+	var tools []string
+	for _, note := range file.Notes {
+		tools = append(tools, fmt.Sprintf("%v:%v", note.Line+1, note.Tool))
+	}
+	if got, want := strings.Join(tools, " "), "3:compiler 5:alloclint"; got != want {
+		t.Errorf("got notes %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// shareCookie remembers the share token after the first request,
+// so that the UI doesn't need to add it to every API call.
+const shareCookie = "share-token"
+
+// Share restricts the index to files inside root, files outside of it,
+// e.g. from GOROOT or the module cache, are removed.
+func (index *Index) Share(root string) {
+	root = index.CanonicalPath("", root)
+	for key, file := range index.Files {
+		if file.Source != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, key)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			delete(index.Files, key)
+		}
+	}
+}
+
+// NewShareToken returns a random token for -share.
+func NewShareToken() string {
+	var token [16]byte
+	if _, err := rand.Read(token[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(token[:])
+}
+
+// authorizeShare checks the token from the ?token= parameter or the cookie
+// set by an earlier request.
+func (server *Server) authorizeShare(w http.ResponseWriter, r *http.Request) bool {
+	token := r.FormValue("token")
+	if token == "" {
+		if cookie, err := r.Cookie(shareCookie); err == nil {
+			token = cookie.Value
+		}
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(server.ShareToken)) != 1 {
+		return false
+	}
+	if r.FormValue("token") != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     shareCookie,
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+	return true
+}
+
+// shareResponseWriter buffers the response to strip the shared root
+// directory from all paths in it.
+type shareResponseWriter struct {
+	http.ResponseWriter
+	roots  []string
+	status int
+	buf    bytes.Buffer
+}
+
+func (w *shareResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *shareResponseWriter) Write(data []byte) (int, error) {
+	return w.buf.Write(data)
+}
+
+// Close writes the response with the root directory removed.
+func (w *shareResponseWriter) Close() error {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	data := w.buf.Bytes()
+	for _, root := range w.roots {
+		data = StripRoot(data, root)
+	}
+	_, err := w.ResponseWriter.Write(data)
+	return err
+}
+
+// StripRoot makes the paths inside root in data relative to it, including
+// paths escaped in JSON and JavaScript strings.
+func StripRoot(data []byte, root string) []byte {
+	sep := string(filepath.Separator)
+	for _, escape := range []func(string) string{
+		func(s string) string { return s },
+		func(s string) string { return strings.Replace(s, `\`, `\\`, -1) },
+		func(s string) string { return strings.Replace(strings.Replace(s, `\`, `\\`, -1), `/`, `\/`, -1) },
+	} {
+		data = bytes.Replace(data, []byte(escape(root+sep)), nil, -1)
+		data = bytes.Replace(data, []byte(escape(root)), []byte("."), -1)
+	}
+	return data
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Layout of snippets, widths are approximate for 12px monospace fonts.
+const (
+	snippetCharWidth  = 7.3
+	snippetLineHeight = 18
+	snippetPadding    = 10
+	snippetMaxColumns = 120
+)
+
+// snippetColors are the colors of the notes by severity.
+var snippetColors = [...]string{Info: "#0a5", Warning: "#c70", Error: "#d33"}
+
+// WriteSnippet writes an SVG image of the line of info with its context and
+// notes, in the style of a code screenshot.
+func WriteSnippet(w io.Writer, info *LineInfo) error {
+	title := fmt.Sprintf("%s:%d", info.Path, info.Line)
+	numberWidth := len(fmt.Sprint(info.Context[len(info.Context)-1].Line))
+
+	var rows []string
+	columns := utf8.RuneCountInString(title)
+	for _, line := range info.Context {
+		rows = append(rows, fmt.Sprintf("%*d  %s", numberWidth, line.Line, snippetText(line.Source)))
+	}
+	for _, note := range info.Notes {
+		rows = append(rows, fmt.Sprintf("%*s  %s", numberWidth, "", snippetText(note.Message)))
+	}
+	for _, row := range rows {
+		if n := utf8.RuneCountInString(row); n > columns {
+			columns = n
+		}
+	}
+
+	width := 2*snippetPadding + int(float64(columns)*snippetCharWidth)
+	height := 2*snippetPadding + snippetLineHeight*(len(rows)+1)
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" role="img" aria-label="%s">
+<title>%[3]s</title>
+<rect width="%[1]d" height="%[2]d" rx="6" fill="#1e1e1e"/>
+<g font-family="Menlo,Consolas,DejaVu Sans Mono,monospace" font-size="12" xml:space="preserve">
+<text x="%[4]d" y="%[5]d" fill="#999">%[3]s</text>
+`, width, height, html.EscapeString(title), snippetPadding, snippetPadding+13)
+
+	y := snippetPadding + snippetLineHeight
+	for i, line := range info.Context {
+		if line.Line == info.Line {
+			fmt.Fprintf(w, "<rect y=\"%d\" width=\"%d\" height=\"%d\" fill=\"#3a3a20\"/>\n", y, width, snippetLineHeight)
+		}
+		fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\" fill=\"#ddd\">%s</text>\n", snippetPadding, y+13, html.EscapeString(rows[i]))
+		y += snippetLineHeight
+	}
+	for i, note := range info.Notes {
+		color := snippetColors[note.Severity]
+		fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\" fill=\"%s\">%s</text>\n", snippetPadding, y+13, color, html.EscapeString(rows[len(info.Context)+i]))
+		y += snippetLineHeight
+	}
+	_, err := fmt.Fprintf(w, "</g>\n</svg>\n")
+	return err
+}
+
+// snippetText expands tabs and cuts text to the width of a snippet.
+func snippetText(text string) string {
+	text = strings.Replace(text, "\t", "    ", -1)
+	if utf8.RuneCountInString(text) > snippetMaxColumns {
+		text = string([]rune(text)[:snippetMaxColumns-1]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// The sources of annotated files are read from Index.Sources, which can be
+// the local file system, a bundle archive (e.g. a *zip.Reader) or an
+// in-memory snapshot. Files are named by their absolute paths in slash form
+// without the leading slash, see SourceName.
+
+// OSFS is the local file system as an fs.FS of absolute paths.
+type OSFS struct{}
+
+func (OSFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return os.Open(sourcePath(name))
+}
+
+// SourceName returns the name of the file at the absolute path in
+// Index.Sources, e.g. "home/user/src/main.go" or "C:/src/main.go".
+func SourceName(path string) string {
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "/")
+}
+
+// sourcePath is the reverse of SourceName for the local file system.
+func sourcePath(name string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.FromSlash(name)
+	}
+	return "/" + name
+}
+
+// sources returns the file system of the sources.
+func (index *Index) sources() fs.FS {
+	if index.Sources == nil {
+		return OSFS{}
+	}
+	return index.Sources
+}
+
+// ReadSource reads the source file at the absolute path.
+func (index *Index) ReadSource(path string) ([]byte, error) {
+	return fs.ReadFile(index.sources(), SourceName(path))
+}
+
+// StatSource returns information about the source file at the absolute path.
+func (index *Index) StatSource(path string) (fs.FileInfo, error) {
+	return fs.Stat(index.sources(), SourceName(path))
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+// TestEmbeddedSources checks that the analyses of Go files read them from
+// Index.Sources, the sources of the demo are only embedded.
+func TestEmbeddedSources(t *testing.T) {
+	index := NewIndex()
+	if err := LoadDemo(index); err != nil {
+		t.Fatal(err)
+	}
+
+	if statuses := index.InlineStatuses(""); len(statuses) == 0 {
+		t.Errorf("no inline statuses")
+	}
+	if symbols := index.Symbols("Checksum"); len(symbols) == 0 {
+		t.Errorf("no symbols matching Checksum")
+	}
+	_, file := index.File(DemoDir, "checksum.go")
+	if funcs := index.fileFuncs(file); len(funcs) == 0 {
+		t.Errorf("no functions in checksum.go")
+	}
+}
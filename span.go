@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Span is the range of columns of an expression on a line,
+// Start is inclusive and End exclusive, 0 is the first column.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// snippetSuffixes and snippetPrefixes surround the source snippet in
+// escape analysis messages, e.g. "&x escapes to heap" or "moved to heap: x".
+var snippetSuffixes = [...]string{
+	" escapes to heap",
+	" does not escape",
+}
+
+var snippetPrefixes = [...]string{
+	"moved to heap: ",
+	"leaking param content: ",
+	"leaking param: ",
+}
+
+// Snippet returns the source expression mentioned in msg, if any.
+func Snippet(msg []byte) string {
+	for _, suffix := range snippetSuffixes {
+		if p := bytes.Index(msg, []byte(suffix)); p > 0 {
+			return string(msg[:p])
+		}
+	}
+	for _, prefix := range snippetPrefixes {
+		if bytes.HasPrefix(msg, []byte(prefix)) {
+			return string(bytes.TrimSpace(msg[len(prefix):]))
+		}
+	}
+	return ""
+}
+
+// FindSpan locates the snippet of msg in source, preferring the occurrence
+// at or after column. Returns nil when the message has no snippet or the
+// compiler printed it differently from the source.
+func FindSpan(source string, column int, msg []byte) *Span {
+	snippet := Snippet(msg)
+	if snippet == "" || strings.Contains(snippet, "...") {
+		return nil
+	}
+
+	start := -1
+	if column >= 0 && column < len(source) {
+		if p := indexWord(source[column:], snippet); p >= 0 {
+			start = column + p
+		}
+	}
+	if start < 0 {
+		start = indexWord(source, snippet)
+	}
+	if start < 0 {
+		return nil
+	}
+	return &Span{Start: start, End: start + len(snippet)}
+}
+
+// indexWord is like strings.Index, but doesn't match identifiers in the
+// middle of a longer identifier, e.g. "x" in "max".
+func indexWord(s, substr string) int {
+	for offset := 0; offset <= len(s); {
+		p := strings.Index(s[offset:], substr)
+		if p < 0 {
+			return -1
+		}
+		start, end := offset+p, offset+p+len(substr)
+		if !(start > 0 && isIdent(s[start-1]) && isIdent(s[start])) &&
+			!(end < len(s) && isIdent(s[end-1]) && isIdent(s[end])) {
+			return start
+		}
+		offset = start + 1
+	}
+	return -1
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteSQL writes the index as an SQL script creating normalized tables,
+// which can be loaded into SQLite for ad-hoc queries:
+//
+//	view-annotated-file -format sql analysis.log | sqlite3 report.db
+//
+// Only the source of annotated lines is included.
+func (index *Index) WriteSQL(w io.Writer) error {
+	fmt.Fprint(w, `BEGIN TRANSACTION;
+CREATE TABLE files (id INTEGER PRIMARY KEY, path TEXT NOT NULL, abspath TEXT NOT NULL, generated INTEGER NOT NULL);
+CREATE TABLE lines (file_id INTEGER NOT NULL REFERENCES files(id), line INTEGER NOT NULL, source TEXT NOT NULL, PRIMARY KEY (file_id, line));
+CREATE TABLE categories (id INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE);
+CREATE TABLE diagnostics (
+	id INTEGER PRIMARY KEY,
+	file_id INTEGER NOT NULL REFERENCES files(id),
+	line INTEGER NOT NULL,
+	column INTEGER,
+	category_id INTEGER REFERENCES categories(id),
+	tool TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	message TEXT NOT NULL,
+	count INTEGER NOT NULL
+);
+CREATE TABLE metadata (key TEXT PRIMARY KEY, value TEXT NOT NULL);
+`)
+
+	meta := index.Metadata
+	for _, kv := range [][2]string{
+		{"go", meta.GoVersion},
+		{"goos", meta.GOOS},
+		{"goarch", meta.GOARCH},
+		{"gcflags", meta.Gcflags},
+		{"preset", meta.Preset},
+		{"commit", meta.Commit},
+		{"time", meta.Time.Format(time.RFC3339)},
+	} {
+		if kv[1] != "" {
+			fmt.Fprintf(w, "INSERT INTO metadata VALUES (%s, %s);\n", sqlString(kv[0]), sqlString(kv[1]))
+		}
+	}
+
+	categories := make(map[string]int)
+	for i, stat := range statSpecs {
+		categories[stat.Name] = i + 1
+		fmt.Fprintf(w, "INSERT INTO categories VALUES (%d, %s);\n", i+1, sqlString(stat.Name))
+	}
+
+	files := index.SortedFiles()
+
+	diagnostic := 0
+	for i, file := range files {
+		id := i + 1
+		generated := 0
+		if file.Generated {
+			generated = 1
+		}
+		fmt.Fprintf(w, "INSERT INTO files VALUES (%d, %s, %s, %d);\n", id, sqlString(file.Path), sqlString(file.AbsPath), generated)
+
+		source := file.Source
+		if source == nil {
+			source, _ = index.ReadSource(file.AbsPath)
+		}
+		lines := SplitLines(source)
+		written := make(map[int]bool)
+
+		for _, note := range file.Notes {
+			if note.Line >= 0 && note.Line < len(lines) && !written[note.Line] {
+				written[note.Line] = true
+				fmt.Fprintf(w, "INSERT INTO lines VALUES (%d, %d, %s);\n", id, note.Line+1, sqlString(string(lines[note.Line])))
+			}
+
+			column, category := "NULL", "NULL"
+			if note.Column >= 0 {
+				column = fmt.Sprint(note.Column + 1)
+			}
+			if name := NoteCategory(&note); name != "" {
+				c, ok := categories[name]
+				if !ok {
+					c = len(categories) + 1
+					categories[name] = c
+					fmt.Fprintf(w, "INSERT INTO categories VALUES (%d, %s);\n", c, sqlString(name))
+				}
+				category = fmt.Sprint(c)
+			}
+			diagnostic++
+			fmt.Fprintf(w, "INSERT INTO diagnostics VALUES (%d, %d, %d, %s, %s, %s, %s, %s, %d);\n",
+				diagnostic, id, note.Line+1, column, category,
+				sqlString(note.Tool), sqlString(note.Severity.String()), sqlString(string(note.Message)), note.Count)
+		}
+	}
+
+	_, err := fmt.Fprint(w, "COMMIT;\n")
+	return err
+}
+
+// sqlString quotes s as an SQL string literal.
+func sqlString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
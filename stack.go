@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+func init() { RegisterParser(20, stackParser{}) }
+
+type stackParser struct{}
+
+func (stackParser) Detect(line []byte) bool {
+	return IsPanicStart(line) || IsGoroutineStart(line)
+}
+
+// Parse handles a panic message followed by the stack trace of the
+// panicking goroutine, or a single goroutine of a stack dump.
+func (stackParser) Parse(index *Index, dir string, lines [][]byte, at int) int {
+	if IsGoroutineStart(lines[at]) {
+		return index.AddGoroutine(dir, lines, at, "")
+	}
+
+	reason := string(lines[at])
+	i := at + 1
+	for i < len(lines) && len(bytes.TrimSpace(lines[i])) == 0 {
+		i++
+	}
+	if i < len(lines) && IsGoroutineStart(lines[i]) {
+		return index.AddGoroutine(dir, lines, i, reason)
+	}
+	return at
+}
+
+// Frame is a single stack frame pointing to a source line.
+type Frame struct {
+	Func string
+	Key  string // index key of the file
+	Path string
+	Line int // 1 is the first line
+}
+
+// Goroutine is a goroutine stack trace, e.g. from a panic.
+type Goroutine struct {
+	Header string // e.g. "goroutine 1 [running]"
+	Reason string // panic message, when the trace follows a panic
+	Frames []Frame
+}
+
+var (
+	panicPrefix = []byte("panic: ")
+	fatalPrefix = []byte("fatal error: ")
+)
+
+// IsPanicStart reports whether line starts a panic or a fatal error.
+func IsPanicStart(line []byte) bool {
+	return bytes.HasPrefix(line, panicPrefix) || bytes.HasPrefix(line, fatalPrefix)
+}
+
+// IsGoroutineStart reports whether line is a goroutine header:
+//
+//	goroutine 1 [running]:
+func IsGoroutineStart(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("goroutine ")) && bytes.HasSuffix(line, []byte("]:"))
+}
+
+// AddGoroutine parses the goroutine stack trace starting at lines[at] and
+// annotates its frames with their position in the stack, returns the index
+// of the last line of the trace.
+func (index *Index) AddGoroutine(dir string, lines [][]byte, at int, reason string) int {
+	g := &Goroutine{
+		Header: string(bytes.TrimSuffix(lines[at], []byte(":"))),
+		Reason: reason,
+	}
+	index.Goroutines = append(index.Goroutines, g)
+
+	var fn []byte
+	i := at + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if len(bytes.TrimSpace(line)) == 0 {
+			break
+		}
+
+		// function lines are not indented, locations are
+		if line[0] != ' ' && line[0] != '\t' {
+			fn = TrimCallArgs(line)
+			continue
+		}
+
+		path, lineno, ok := ParseFrameLine(bytes.TrimSpace(line))
+		if !ok {
+			continue
+		}
+
+		key, file := index.File(dir, string(path))
+		frame := Frame{
+			Func: string(fn),
+			Key:  key,
+			Path: file.Path,
+			Line: lineno,
+		}
+
+		msg := fmt.Sprintf("%s frame %d: %s", g.Header, len(g.Frames), frame.Func)
+		if len(g.Frames) == 0 && reason != "" {
+			msg += ": " + reason
+		}
+		g.Frames = append(g.Frames, frame)
+
+		if file.AddNote("panic", lineno-1, -1, []byte(msg)) {
+			file.Stats.Add([]byte(msg))
+		}
+	}
+	return i
+}
+
+// ParseFrameLine parses the location line of a stack frame:
+//
+//	/go/src/abc.go:688 +0x3c
+//	C:\Go\src\abc.go:688
+func ParseFrameLine(line []byte) (path []byte, lineno int, ok bool) {
+	if p := bytes.LastIndex(line, []byte(" +0x")); p >= 0 {
+		line = line[:p]
+	}
+	colon := bytes.LastIndexByte(line, ':')
+	if colon <= 0 {
+		return nil, -1, false
+	}
+	lineno, ok = ParseInt(line[colon+1:])
+	return line[:colon], lineno, ok
+}
+
+// TrimCallArgs removes the argument list from a function line of a stack frame:
+//
+//	main.main.func1(0xc0000a0010, 0x1)
+func TrimCallArgs(line []byte) []byte {
+	if p := bytes.LastIndexByte(line, '('); p > 0 {
+		return line[:p]
+	}
+	return line
+}
@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestStackParser(t *testing.T) {
+	checkParser(t, stackParser{}, `
+panic: runtime error: index out of range [5] with length 3
+
+goroutine 1 [running]:
+main.get(...)
+	/src/main.go:15
+main.main()
+	/src/main.go:20 +0x1d
+exit status 2`, []string{
+		"main.go:15:0: panic: goroutine 1 [running] frame 0: main.get: panic: runtime error: index out of range [5] with length 3",
+		"main.go:20:0: panic: goroutine 1 [running] frame 1: main.main",
+	})
+}
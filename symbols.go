@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxSymbols limits the functions found by a symbol search.
+const maxSymbols = 50
+
+// Symbol is a function found by a symbol search, with the inlining decision
+// about it, its call sites and the calls it makes, to judge the impact of
+// changing its size.
+type Symbol struct {
+	InlineStatus
+	// Notes is the number of annotations in the function.
+	Notes int `json:"notes"`
+	// Calls are the call sites of the function, Callees the calls in it.
+	Calls   []CallSite `json:"calls"`
+	Callees []CallSite `json:"callees"`
+}
+
+// CallSite is a call of a function, Key is empty when the file isn't
+// annotated. Callee is qualified by its package when it is in another
+// package than the caller, e.g. "os.(*File).Write".
+type CallSite struct {
+	Key     string `json:"key"`
+	Path    string `json:"path"`
+	Line    int    `json:"line"` // 1 is the first line
+	Caller  string `json:"caller"`
+	Callee  string `json:"callee"`
+	Inlined bool   `json:"inlined"`
+}
+
+// funcDecl is a function declared in a type-checked package, Name is like
+// the compiler reports it, e.g. "(*T).Name", End the line of its end.
+type funcDecl struct {
+	Package string
+	Name    string
+	Path    string
+	Line    int
+	End     int
+}
+
+// symbolCall is a call in a function of a type-checked package, caller and
+// callee are the positionKey of the declarations and column is that of the
+// parenthesis.
+type symbolCall struct {
+	caller, callee string
+	site           CallSite
+	column         int
+}
+
+// Symbols finds the functions and methods whose name contains query, case
+// insensitively, in the packages of the annotated Go files. The packages are
+// type-checked to find the call sites of the functions in them, which are
+// marked as inlined when the compiler reported "inlining call to" there.
+// The functions and calls are found once per generation of the index, a
+// search only filters them.
+func (index *Index) Symbols(query string) []Symbol {
+	query = strings.ToLower(query)
+	packages := index.CheckedPackages()
+
+	// symbols by the position of their name, "path:line"
+	symbols := make(map[string]*Symbol)
+	for _, pkg := range packages {
+		for _, fn := range pkg.funcs {
+			if strings.Contains(strings.ToLower(fn.Name), query) {
+				symbols[positionKey(fn.Path, fn.Line)] = index.symbol(fn.Package, fn.Name, fn.Path, fn.Line, fn.End)
+			}
+		}
+	}
+
+	for _, pkg := range packages {
+		for _, call := range pkg.calls {
+			caller, callee := symbols[call.caller], symbols[call.callee]
+			if caller == nil && callee == nil {
+				continue
+			}
+			site := call.site
+			site.Path = filepath.ToSlash(site.Path)
+			if file, ok := index.Lookup(index.CanonicalPath("", call.site.Path)); ok {
+				site.Key, site.Path = file.Key, file.Path
+				site.Inlined = inlinedCall(file, site.Callee, site.Line, call.column)
+			}
+			if callee != nil {
+				callee.Calls = append(callee.Calls, site)
+			}
+			if caller != nil {
+				caller.Callees = append(caller.Callees, site)
+			}
+		}
+	}
+
+	result := []Symbol{}
+	for _, symbol := range symbols {
+		sortCallSites(symbol.Calls)
+		sortCallSites(symbol.Callees)
+		result = append(result, *symbol)
+	}
+	sort.Slice(result, func(i, k int) bool {
+		a, b := &result[i], &result[k]
+		if a.Func != b.Func {
+			return a.Func < b.Func
+		}
+		return a.Path < b.Path
+	})
+	if len(result) > maxSymbols {
+		result = result[:maxSymbols]
+	}
+	return result
+}
+
+// funcSymbols returns the functions declared in the type-checked files and
+// the calls in them whose callee could be resolved.
+func funcSymbols(fset *token.FileSet, files []*ast.File, info *types.Info) ([]funcDecl, []symbolCall) {
+	var funcs []funcDecl
+	var calls []symbolCall
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			name := fn.Name.Name
+			if fn.Recv != nil && len(fn.Recv.List) > 0 {
+				name = "(" + receiverType(fn.Recv.List[0].Type) + ")." + name
+			}
+			callerPos := fset.Position(fn.Name.Pos())
+			funcs = append(funcs, funcDecl{
+				Package: file.Name.Name,
+				Name:    name,
+				Path:    callerPos.Filename,
+				Line:    callerPos.Line,
+				End:     fset.Position(fn.End()).Line,
+			})
+
+			caller := positionKey(callerPos.Filename, callerPos.Line)
+			var pkg *types.Package
+			if obj := info.Defs[fn.Name]; obj != nil {
+				pkg = obj.Pkg()
+			}
+			ast.Inspect(fn, func(n ast.Node) bool {
+				expr, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				var ident *ast.Ident
+				switch fun := expr.Fun.(type) {
+				case *ast.Ident:
+					ident = fun
+				case *ast.SelectorExpr:
+					ident = fun.Sel
+				default:
+					return true
+				}
+				callee, ok := info.Uses[ident].(*types.Func)
+				if !ok {
+					return true
+				}
+				def := fset.Position(callee.Pos())
+				pos := fset.Position(expr.Lparen)
+				calls = append(calls, symbolCall{
+					caller: caller,
+					callee: positionKey(def.Filename, def.Line),
+					site: CallSite{
+						Path:   pos.Filename,
+						Line:   pos.Line,
+						Caller: name,
+						Callee: funcName(callee, pkg),
+					},
+					column: pos.Column,
+				})
+				return true
+			})
+		}
+	}
+	return funcs, calls
+}
+
+// symbol returns the symbol of the function name declared in path from
+// line to end.
+func (index *Index) symbol(pkg, name, path string, line, end int) *Symbol {
+	dir := filepath.Dir(path)
+	symbol := &Symbol{
+		InlineStatus: InlineStatus{
+			Path:    filepath.ToSlash(path),
+			Line:    line,
+			Package: pkg,
+			Dir:     dir,
+			Func:    name,
+			Status:  "unknown",
+		},
+		Calls:   []CallSite{},
+		Callees: []CallSite{},
+	}
+	file, ok := index.Lookup(index.CanonicalPath("", path))
+	if !ok {
+		return symbol
+	}
+	symbol.Key, symbol.Path, symbol.Dir = file.Key, file.Path, filepath.Dir(file.Path)
+	for _, note := range file.Notes {
+		if note.Line+1 == line {
+			symbol.parse(name, note.Message)
+		}
+		if line <= note.Line+1 && note.Line+1 <= end {
+			symbol.Notes++
+		}
+	}
+	return symbol
+}
+
+func sortCallSites(sites []CallSite) {
+	sort.Slice(sites, func(i, k int) bool {
+		a, b := &sites[i], &sites[k]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Callee < b.Callee
+	})
+}
+
+// funcName returns the name of fn like the compiler reports it, e.g.
+// "(*T).Name", qualified by the package name outside of pkg.
+func funcName(fn *types.Func, pkg *types.Package) string {
+	qualifier := func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		return p.Name()
+	}
+	name := fn.Name()
+	if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+		recvType := recv.Type()
+		pointer := ""
+		if ptr, ok := recvType.(*types.Pointer); ok {
+			recvType, pointer = ptr.Elem(), "*"
+		}
+		if named, ok := recvType.(*types.Named); ok {
+			name = "(" + pointer + named.Obj().Name() + ")." + name
+		} else {
+			// methods of interfaces
+			name = types.TypeString(recvType, qualifier) + "." + name
+		}
+	}
+	if fn.Pkg() != nil && qualifier(fn.Pkg()) != "" {
+		name = fn.Pkg().Name() + "." + name
+	}
+	return name
+}
+
+// inlinedCall reports whether the compiler inlined the call of function
+// name at line and column of file. The column of the call is that of the
+// parenthesis, which is ignored when the notes have no columns.
+func inlinedCall(file *File, name string, line, column int) bool {
+	for _, note := range file.Notes {
+		if note.Line+1 != line || (note.Column >= 0 && note.Column+1 != column) {
+			continue
+		}
+		callee := strings.TrimPrefix(string(note.Message), "inlining call to ")
+		if len(callee) == len(note.Message) {
+			continue
+		}
+		if callee == name || strings.HasSuffix(callee, "."+name) || strings.HasSuffix(name, "."+callee) {
+			return true
+		}
+	}
+	return false
+}
+
+func positionKey(path string, line int) string {
+	return path + ":" + strconv.Itoa(line)
+}
+
+// serveSymbols responds with the functions matching ?q=.
+func (server *Server) serveSymbols(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.FormValue("q"))
+	if query == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "No query specified.")
+		return
+	}
+	writeJSON(w, server.Index.Symbols(query))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/csv"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"path/filepath"
+	"strconv"
+)
+
+// WriteTable writes all notes as CSV, or TSV when comma is '\t', for
+// triaging in spreadsheets. Notes in Go files include the enclosing
+// function and the package name, all notes their Fingerprint.
+func (index *Index) WriteTable(w io.Writer, comma rune) error {
+	out := csv.NewWriter(w)
+	out.Comma = comma
+	out.Write([]string{"path", "line", "column", "category", "tool", "severity", "message", "function", "package", "fingerprint"})
+
+	files := index.SortedFiles()
+
+	for _, file := range files {
+		var pkg string
+		var funcs []funcRange
+		if file.Source == nil && filepath.Ext(file.AbsPath) == ".go" {
+			pkg, funcs = index.goFuncs(file.AbsPath)
+		}
+
+		for _, note := range file.Notes {
+			column := ""
+			if note.Column >= 0 {
+				column = strconv.Itoa(note.Column + 1)
+			}
+			out.Write([]string{
+				file.Path,
+				strconv.Itoa(note.Line + 1),
+				column,
+				NoteCategory(&note),
+				note.Tool,
+				note.Severity.String(),
+				string(note.Message),
+				enclosingFunc(funcs, note.Line+1),
+				pkg,
+				Fingerprint(file.Path, funcs, &note),
+			})
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// funcRange is the lines of a function declaration, 1 is the first line.
+type funcRange struct {
+	Name     string
+	From, To int
+}
+
+// goFuncs returns the package name and functions of a Go file read from
+// the sources, nothing when it cannot be read or parsed.
+func (index *Index) goFuncs(path string) (string, []funcRange) {
+	src, err := index.ReadSource(path)
+	if err != nil {
+		return "", nil
+	}
+	return parseFuncs(path, src)
+}
+
+// parseFuncs returns the package name and functions of the Go source src
+// of the file at path, nothing when it cannot be parsed.
+func parseFuncs(path string, src []byte) (string, []funcRange) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+	if err != nil {
+		return "", nil
+	}
+
+	var funcs []funcRange
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		name := fn.Name.Name
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			name = "(" + receiverType(fn.Recv.List[0].Type) + ")." + name
+		}
+		funcs = append(funcs, funcRange{
+			Name: name,
+			From: fset.Position(fn.Pos()).Line,
+			To:   fset.Position(fn.End()).Line,
+		})
+	}
+	return file.Name.Name, funcs
+}
+
+// receiverType formats a receiver type, e.g. "*T" or "T[K]".
+func receiverType(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + receiverType(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	case *ast.IndexExpr:
+		return receiverType(expr.X)
+	case *ast.IndexListExpr:
+		return receiverType(expr.X)
+	case *ast.ParenExpr:
+		return receiverType(expr.X)
+	}
+	return "?"
+}
+
+func enclosingFunc(funcs []funcRange, line int) string {
+	for _, fn := range funcs {
+		if fn.From <= line && line <= fn.To {
+			return fn.Name
+		}
+	}
+	return ""
+}
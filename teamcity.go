@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// teamcitySeverity maps severities to the SEVERITY attribute of inspections.
+var teamcitySeverity = map[Severity]string{
+	Info:    "INFO",
+	Warning: "WARNING",
+	Error:   "ERROR",
+}
+
+// WriteTeamCity writes all notes as TeamCity service messages, which show
+// up in the Inspections tab of the build. Each tool and category, e.g.
+// "compiler/inlining", is a separate inspection type.
+func (index *Index) WriteTeamCity(w io.Writer) error {
+	files := index.SortedFiles()
+
+	types := make(map[string]bool)
+	for _, file := range files {
+		for _, note := range file.Notes {
+			category := NoteCategory(&note)
+			id := note.Tool
+			if category != "" {
+				id += "/" + category
+			}
+			if !types[id] {
+				types[id] = true
+				if category == "" {
+					category = note.Tool
+				}
+				fmt.Fprintf(w, "##teamcity[inspectionType id='%s' name='%s' category='%s' description='%s']\n",
+					teamcityEscape(id), teamcityEscape(id), teamcityEscape(category),
+					teamcityEscape("Annotations of "+note.Tool))
+			}
+
+			_, err := fmt.Fprintf(w, "##teamcity[inspection typeId='%s' message='%s' file='%s' line='%d' SEVERITY='%s']\n",
+				teamcityEscape(id), teamcityEscape(string(note.Message)),
+				teamcityEscape(filepath.ToSlash(file.Path)), note.Line+1, teamcitySeverity[note.Severity])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var teamcityEscaper = strings.NewReplacer(
+	"|", "||",
+	"'", "|'",
+	"\n", "|n",
+	"\r", "|r",
+	"[", "|[",
+	"]", "|]",
+)
+
+// teamcityEscape escapes a service message attribute value.
+func teamcityEscape(s string) string {
+	return teamcityEscaper.Replace(s)
+}
@@ -0,0 +1,22 @@
+package main
+
+import "fmt"
+
+type point struct{ x, y int }
+
+func newPoint(x, y int) *point {
+	return &point{x, y}
+}
+
+func sum(values []int) int {
+	total := 0
+	for i := range values {
+		total += values[i]
+	}
+	return total
+}
+
+func main() {
+	p := newPoint(1, 2)
+	fmt.Println(p, sum([]int{p.x, p.y}))
+}
@@ -0,0 +1,7 @@
+package main
+
+import "fmt"
+
+func describe(p *point) string {
+	return fmt.Sprintf("%d,%s", p.x, p.y)
+}
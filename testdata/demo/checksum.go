@@ -0,0 +1,39 @@
+package main
+
+// Checksum reads four bytes at every index, each needs a bounds check.
+func Checksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i+3 < len(data); i += 4 {
+		sum += uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+	}
+	return sum
+}
+
+// ChecksumHinted checks the largest index first, so that the compiler
+// can prove the other indices in bounds.
+func ChecksumHinted(data []byte) uint32 {
+	var sum uint32
+	for len(data) >= 4 {
+		_ = data[3]
+		sum += uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16 | uint32(data[3])<<24
+		data = data[4:]
+	}
+	return sum
+}
+
+// fib is recursive and cannot be inlined.
+func fib(n int) int {
+	if n < 2 {
+		return n
+	}
+	return fib(n-1) + fib(n-2)
+}
+
+// Buffers allocates a buffer of a size unknown at compile time.
+func Buffers(n int) [][]byte {
+	buffers := make([][]byte, n)
+	for i := range buffers {
+		buffers[i] = make([]byte, fib(i))
+	}
+	return buffers
+}
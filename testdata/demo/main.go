@@ -0,0 +1,22 @@
+// Command inventory is a small sample program for the -demo mode of
+// view-annotated-file, written to show the common compiler annotations.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	store := NewStore()
+	for i, name := range []string{"apple", "pear", "plum"} {
+		store.Add(Item{Name: name, Count: i + 1})
+	}
+
+	fmt.Println(store.Describe("pear"))
+	fmt.Printf("total items: %s\n", store.Total())
+
+	report := store.Report(func(item Item) bool { return item.Count > 1 })
+	os.Stdout.WriteString(strings.Join(report, "\n") + "\n")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Item is a product in the store.
+type Item struct {
+	Name  string
+	Count int
+}
+
+// Store keeps items by name.
+type Store struct {
+	items map[string]*Item
+	names []string
+}
+
+// NewStore returns an empty store, the store escapes to the heap because
+// a pointer to it is returned.
+func NewStore() *Store {
+	return &Store{items: make(map[string]*Item)}
+}
+
+// Add adds an item, the copy stored in the map escapes to the heap.
+func (store *Store) Add(item Item) {
+	if existing, ok := store.items[item.Name]; ok {
+		existing.Count += item.Count
+		return
+	}
+	store.items[item.Name] = &item
+	store.names = append(store.names, item.Name)
+}
+
+// Count is small enough to be inlined.
+func (store *Store) Count(name string) int {
+	if item, ok := store.items[name]; ok {
+		return item.Count
+	}
+	return 0
+}
+
+// Total sums all counts.
+func (store *Store) Total() int {
+	total := 0
+	for _, name := range store.names {
+		total += store.Count(name)
+	}
+	return total
+}
+
+// Describe converts the count to an interface for fmt.Sprintf.
+func (store *Store) Describe(name string) string {
+	return fmt.Sprintf("%s: %d", name, store.Count(name))
+}
+
+// Report returns the names of the items matching keep, the closure passed
+// in does not escape.
+func (store *Store) Report(keep func(Item) bool) []string {
+	names := make([]string, 0, len(store.names))
+	for _, name := range store.names {
+		if keep(*store.items[name]) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+func init() { RegisterParser(30, testParser{}) }
+
+type testParser struct{}
+
+func (testParser) Detect(line []byte) bool { return IsTestFailure(line) }
+
+func (testParser) Parse(index *Index, dir string, lines [][]byte, at int) int {
+	return index.AddTestFailure(dir, lines, at)
+}
+
+var testFailPrefix = []byte("--- FAIL: ")
+
+// IsTestFailure reports whether line starts a failed test in go test output:
+//
+//	--- FAIL: TestFoo (0.00s)
+func IsTestFailure(line []byte) bool {
+	return bytes.HasPrefix(line, testFailPrefix)
+}
+
+type testFailure struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+// AddTestFailure parses the output of a failed test starting at lines[at]
+// and annotates the test files with failure messages, returns the index
+// of the last line of the test output.
+func (index *Index) AddTestFailure(dir string, lines [][]byte, at int) int {
+	test := testName(lines[at])
+
+	var failures []*testFailure
+	i := at + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if len(line) == 0 || (line[0] != ' ' && line[0] != '\t') {
+			break
+		}
+
+		trimmed := bytes.TrimSpace(line)
+		if IsTestFailure(trimmed) {
+			test = testName(trimmed)
+			continue
+		}
+
+		path, lineno, _, msg, ok := ParseFileLine(trimmed)
+		if ok && bytes.HasSuffix(path, []byte("_test.go")) {
+			failures = append(failures, &testFailure{
+				Path:    string(path),
+				Line:    lineno,
+				Message: "FAIL " + test + ": " + string(msg),
+			})
+			continue
+		}
+
+		// continuation of a multi-line message
+		if len(failures) > 0 {
+			failures[len(failures)-1].Message += "\n" + string(trimmed)
+		}
+	}
+
+	importPath := testPackage(lines[i:])
+	for _, failure := range failures {
+		path := index.ResolveTestFile(dir, importPath, failure.Path)
+		_, file := index.File(dir, path)
+		if file.AddNote("test", failure.Line-1, -1, []byte(failure.Message)) {
+			file.Stats.Add([]byte(failure.Message))
+		}
+	}
+	return i - 1
+}
+
+// testName extracts the test name from "--- FAIL: TestFoo (0.00s)".
+func testName(line []byte) string {
+	name := bytes.TrimPrefix(line, testFailPrefix)
+	if p := bytes.LastIndex(name, []byte(" (")); p >= 0 {
+		name = name[:p]
+	}
+	return string(name)
+}
+
+// testPackage finds the import path of the package from the summary line
+// following the test output:
+//
+//	FAIL	example.com/pkg	0.012s
+func testPackage(lines [][]byte) string {
+	for _, line := range lines {
+		if bytes.HasPrefix(line, []byte("FAIL\t")) || bytes.HasPrefix(line, []byte("ok  \t")) {
+			fields := strings.Fields(string(line))
+			if len(fields) >= 2 {
+				return fields[1]
+			}
+		}
+	}
+	return ""
+}
+
+// ResolveTestFile finds the file for base name reported by go test, which
+// only prints names relative to the package directory. When there are several
+// candidates under dir, the one matching the package import path is used.
+func (index *Index) ResolveTestFile(dir string, importPath string, base string) string {
+	if strings.ContainsAny(base, `/\`) {
+		return base
+	}
+
+	if index.testFiles == nil {
+		index.testFiles = make(map[string][]string)
+		root := SourceName(dir)
+		if root == "" {
+			root = "."
+		}
+		fs.WalkDir(index.sources(), root, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if entry.IsDir() && name != root && strings.HasPrefix(entry.Name(), ".") {
+				return fs.SkipDir
+			}
+			if strings.HasSuffix(name, "_test.go") {
+				path := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(name, root)))
+				index.testFiles[entry.Name()] = append(index.testFiles[entry.Name()], path)
+			}
+			return nil
+		})
+	}
+
+	best, bestLength := base, -1
+	for _, candidate := range index.testFiles[base] {
+		rel, err := filepath.Rel(dir, filepath.Dir(candidate))
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+
+		matches := rel == "." || importPath == "" || importPath == rel || strings.HasSuffix(importPath, "/"+rel)
+		if matches && len(rel) > bestLength {
+			best, bestLength = candidate, len(rel)
+		}
+	}
+	return best
+}
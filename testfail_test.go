@@ -0,0 +1,12 @@
+package main
+
+import "testing"
+
+func TestTestFailureParser(t *testing.T) {
+	checkParser(t, testParser{}, `
+--- FAIL: TestGet (0.00s)
+    main_test.go:12: got 1, want 2
+FAIL`, []string{
+		"main_test.go:12:0: test: FAIL TestGet: got 1, want 2",
+	})
+}
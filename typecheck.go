@@ -0,0 +1,165 @@
+package main
+
+import (
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// checkedPackage is what the analyses of the annotated Go files need from
+// a type-checked package, the syntax trees and type information are not
+// kept.
+type checkedPackage struct {
+	Dir string
+
+	// interfaceCalls are the calls of interface methods, see Devirtualization.
+	interfaceCalls []interfaceCall
+	// values are the types of values by position, see EscapesByType.
+	values map[string]valueType
+	// funcs are the declared functions and calls the calls in them,
+	// see Symbols.
+	funcs []funcDecl
+	calls []symbolCall
+}
+
+// typeChecked are the packages checked for a generation of the index.
+type typeChecked struct {
+	generation int
+	packages   []*checkedPackage
+}
+
+// CheckedPackages returns the type-checked packages of the annotated Go
+// files, which are checked once per generation of the index. Checking takes
+// long for large projects, the server does it without holding its lock,
+// see lockTypeChecked.
+func (index *Index) CheckedPackages() []*checkedPackage {
+	if !index.typeChecked() {
+		dirs := index.PackageDirs()
+		index.setCheckedPackages(dirs, checkPackages(index.sources(), dirs))
+	}
+	return index.checked.packages
+}
+
+// typeChecked reports whether the packages are checked for the current
+// generation.
+func (index *Index) typeChecked() bool {
+	return index.checked != nil && index.checked.generation == index.Generation
+}
+
+// setCheckedPackages caches the packages checked in dirs for the current
+// generation, unless the directories of the annotated files changed while
+// they were being checked.
+func (index *Index) setCheckedPackages(dirs []string, packages []*checkedPackage) {
+	current := index.PackageDirs()
+	if strings.Join(current, "\n") != strings.Join(dirs, "\n") {
+		return
+	}
+	index.checked = &typeChecked{
+		generation: index.Generation,
+		packages:   packages,
+	}
+}
+
+// PackageDirs returns the sorted directories of the annotated Go files,
+// except those in GOROOT and the module cache, which are not the project.
+func (index *Index) PackageDirs() []string {
+	var skip []string
+	for _, dir := range []string{index.Goroot(), index.moduleCache()} {
+		if dir != "" {
+			skip = append(skip, filepath.Clean(dir)+string(filepath.Separator))
+		}
+	}
+
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, file := range index.Files {
+		if file.Source != nil || filepath.Ext(file.AbsPath) != ".go" {
+			continue
+		}
+		dir := filepath.Dir(file.AbsPath)
+		if seen[dir] || hasAnyPrefix(dir+string(filepath.Separator), skip) {
+			continue
+		}
+		seen[dir] = true
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// moduleCache returns the directory of the Go module cache, found on the
+// first use.
+func (index *Index) moduleCache() string {
+	if index.modcache != nil {
+		return *index.modcache
+	}
+	dir := os.Getenv("GOMODCACHE")
+	if out, err := exec.Command("go", "env", "GOMODCACHE").Output(); err == nil {
+		dir = strings.TrimSpace(string(out))
+	} else if gopath := filepath.SplitList(build.Default.GOPATH); dir == "" && len(gopath) > 0 {
+		dir = filepath.Join(gopath[0], "pkg", "mod")
+	}
+	index.modcache = &dir
+	return dir
+}
+
+// checkPackages type-checks the packages in dirs of the sources. The
+// dependencies are imported from source once for all of them.
+func checkPackages(sources fs.FS, dirs []string) []*checkedPackage {
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "source", nil)
+
+	var packages []*checkedPackage
+	for _, dir := range dirs {
+		info := &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		}
+		files := checkPackage(fset, imp, sources, dir, info)
+		pkg := &checkedPackage{
+			Dir:            dir,
+			interfaceCalls: interfaceCalls(fset, files, info),
+			values:         valueTypes(fset, info),
+		}
+		pkg.funcs, pkg.calls = funcSymbols(fset, files, info)
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+// lockTypeChecked locks mu once the packages of the index are checked for
+// its current generation. The packages are checked without holding mu, so
+// that other requests are served meanwhile, and one at a time.
+func (server *Server) lockTypeChecked() {
+	server.checking.Lock()
+	defer server.checking.Unlock()
+
+	server.mu.Lock()
+	for !server.Index.typeChecked() {
+		dirs := server.Index.PackageDirs()
+		sources := server.Index.sources()
+		server.mu.Unlock()
+		packages := checkPackages(sources, dirs)
+		server.mu.Lock()
+		server.Index.setCheckedPackages(dirs, packages)
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+// maxUnparsedLines is how many unparsed lines are kept as examples.
+const maxUnparsedLines = 100
+
+// UnparsedLines are the log lines that no parser understood, to tell when
+// the format of a log isn't supported.
+type UnparsedLines struct {
+	Count int      `json:"count"`
+	Lines []string `json:"lines"` // the first maxUnparsedLines lines
+}
+
+func (index *Index) addUnparsed(line []byte) {
+	index.Unparsed.Count++
+	if len(index.Unparsed.Lines) < maxUnparsedLines {
+		index.Unparsed.Lines = append(index.Unparsed.Lines, string(line))
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// version is the released version, set with
+// -ldflags "-X main.version=v1.2.3" or taken from the module version of go
+// install.
+var version = ""
+
+// releaseRepo is the GitHub repository with the releases of the binaries.
+const releaseRepo = "loov/view-annotated-file"
+
+// Version returns the version of the binary with the VCS information
+// embedded by the go command, e.g. "v1.2.3 (1a2b3c4d5e6f, 2024-01-02T15:04:05Z)"
+// or "(devel) (1a2b3c4d5e6f+dirty, ...)" for local builds.
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	v := version
+	if v == "" && ok {
+		v = info.Main.Version
+	}
+	if v == "" {
+		v = "(devel)"
+	}
+	if !ok {
+		return v
+	}
+
+	settings := make(map[string]string)
+	for _, setting := range info.Settings {
+		settings[setting.Key] = setting.Value
+	}
+	revision := settings["vcs.revision"]
+	if revision == "" {
+		return v
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if settings["vcs.modified"] == "true" {
+		revision += "+dirty"
+	}
+	if t := settings["vcs.time"]; t != "" {
+		revision += ", " + t
+	}
+	return v + " (" + revision + ")"
+}
+
+// PrintVersion implements the version subcommand.
+func PrintVersion(w io.Writer) {
+	fmt.Fprintf(w, "view-annotated-file %s %s %s/%s\n", Version(), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// releaseAsset returns the name of the binary of the platform attached to
+// releases, e.g. view-annotated-file-linux-amd64.
+func releaseAsset(goos, goarch string) string {
+	name := "view-annotated-file-" + goos + "-" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// SelfUpdate implements the self-update subcommand: it replaces the running
+// binary with the one of the latest GitHub release for the platform, after
+// checking it against the SHA256SUMS of the release. Nothing is downloaded
+// unless the subcommand is run.
+func SelfUpdate(args []string, w io.Writer) error {
+	set := flag.NewFlagSet("self-update", flag.ExitOnError)
+	repo := set.String("repo", releaseRepo, "GitHub repository with the releases")
+	token := set.String("token", os.Getenv("GITHUB_TOKEN"), "GitHub token, defaults to $GITHUB_TOKEN")
+	check := set.Bool("check", false, "only report whether a newer release exists")
+	force := set.Bool("force", false, "install the latest release even when it is older than the running binary or its version is unknown")
+	set.Parse(args)
+
+	api := os.Getenv("GITHUB_API_URL")
+	if api == "" {
+		api = "https://api.github.com"
+	}
+	data, err := githubGet(api+"/repos/"+*repo+"/releases/latest", *token)
+	if err != nil {
+		return err
+	}
+	var release struct {
+		TagName string `json:"tag_name"`
+		Assets  []struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.Unmarshal(data, &release); err != nil {
+		return err
+	}
+
+	latest, ok := parseSemver(release.TagName)
+	if !ok {
+		return fmt.Errorf("self-update: release %s is not a semantic version", release.TagName)
+	}
+	current := strings.Fields(Version())[0]
+	running, known := parseSemver(current)
+	switch {
+	case !known:
+		if *check {
+			fmt.Fprintf(w, "view-annotated-file %s is the latest release, running %s.\n", release.TagName, current)
+			return nil
+		}
+		if !*force {
+			return fmt.Errorf("self-update: version %s of the binary is unknown, use -force to install %s", current, release.TagName)
+		}
+	case latest.compare(running) == 0:
+		fmt.Fprintf(w, "view-annotated-file %s is the latest release.\n", current)
+		return nil
+	case latest.compare(running) < 0:
+		fmt.Fprintf(w, "view-annotated-file %s is newer than the latest release %s.\n", current, release.TagName)
+		if *check {
+			return nil
+		}
+		if !*force {
+			return fmt.Errorf("self-update: not downgrading, use -force to install %s", release.TagName)
+		}
+	case *check:
+		fmt.Fprintf(w, "view-annotated-file %s is available, running %s.\n", release.TagName, current)
+		return nil
+	}
+
+	asset := releaseAsset(runtime.GOOS, runtime.GOARCH)
+	urls := make(map[string]string)
+	for _, a := range release.Assets {
+		urls[a.Name] = a.URL
+	}
+	if urls[asset] == "" {
+		return fmt.Errorf("self-update: release %s has no binary %s", release.TagName, asset)
+	}
+	if urls["SHA256SUMS"] == "" {
+		return fmt.Errorf("self-update: release %s has no SHA256SUMS", release.TagName)
+	}
+
+	sums, err := download(urls["SHA256SUMS"])
+	if err != nil {
+		return err
+	}
+	want := ""
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		// sha256sum output: "<hex>  <name>", binary files marked with '*'
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset {
+			want = fields[0]
+		}
+	}
+	if want == "" {
+		return fmt.Errorf("self-update: SHA256SUMS of %s has no checksum of %s", release.TagName, asset)
+	}
+
+	binary, err := download(urls[asset])
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(binary)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("self-update: checksum of %s is %s, expected %s", asset, got, want)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	if err := replaceExecutable(exe, binary); err != nil {
+		return fmt.Errorf("self-update: %v", err)
+	}
+	fmt.Fprintf(w, "Updated %s from %s to %s.\n", exe, current, release.TagName)
+	return nil
+}
+
+// semver is a semantic version, e.g. v1.2.3-rc.1, pseudo-versions like
+// v0.0.0-20240102150405-1a2b3c4d5e6f are versions with a pre-release.
+type semver struct {
+	major, minor, patch int
+	prerelease          []string
+}
+
+// parseSemver parses a semantic version with the "v" prefix of Go modules,
+// build metadata is ignored. False for "(devel)" and other non-versions.
+func parseSemver(v string) (semver, bool) {
+	var version semver
+	if !strings.HasPrefix(v, "v") {
+		return version, false
+	}
+	v = v[1:]
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		version.prerelease = strings.Split(v[i+1:], ".")
+		v = v[:i]
+		for _, id := range version.prerelease {
+			if id == "" {
+				return version, false
+			}
+		}
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return version, false
+	}
+	numbers := []*int{&version.major, &version.minor, &version.patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || part[0] == '+' {
+			return version, false
+		}
+		*numbers[i] = n
+	}
+	return version, true
+}
+
+// compare returns -1, 0 or 1 when version is older than, the same as or
+// newer than other, with the precedence of semantic versioning.
+func (version semver) compare(other semver) int {
+	for _, d := range []int{version.major - other.major, version.minor - other.minor, version.patch - other.patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	// a pre-release is older than the release
+	switch {
+	case len(version.prerelease) == 0 && len(other.prerelease) == 0:
+		return 0
+	case len(version.prerelease) == 0:
+		return 1
+	case len(other.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(version.prerelease) && i < len(other.prerelease); i++ {
+		a, b := version.prerelease[i], other.prerelease[i]
+		if a == b {
+			continue
+		}
+		an, aerr := strconv.Atoi(a)
+		bn, berr := strconv.Atoi(b)
+		switch {
+		case aerr == nil && berr == nil:
+			return sign(an - bn)
+		case aerr == nil:
+			// numeric identifiers are older than alphanumeric ones
+			return -1
+		case berr == nil:
+			return 1
+		case a < b:
+			return -1
+		default:
+			return 1
+		}
+	}
+	return sign(len(version.prerelease) - len(other.prerelease))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// replaceExecutable writes the binary next to exe and renames it over exe.
+// A running executable cannot be overwritten on Windows, but renamed.
+func replaceExecutable(exe string, binary []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".view-annotated-file-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0o111); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), exe)
+}
+
+// download returns the content at url, unlike OpenURL without the -header
+// meant for logs.
+func download(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %v: %v", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
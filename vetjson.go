@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+func init() { RegisterParser(60, vetJSONParser{}) }
+
+// vetJSONParser handles the output of go vet -json, a JSON object per
+// package mapping analyzer names to their diagnostics:
+//
+//	{
+//		"example.com/pkg": {
+//			"printf": [
+//				{
+//					"posn": "/src/pkg/foo.go:10:2",
+//					"message": "fmt.Println call has possible Printf formatting directive %d"
+//				}
+//			]
+//		}
+//	}
+//
+// Notes are labeled with the analyzer, e.g. "vet/printf".
+type vetJSONParser struct{}
+
+// VetDiagnostic is a diagnostic in go vet -json output.
+type VetDiagnostic struct {
+	Posn    string `json:"posn"`
+	Message string `json:"message"`
+}
+
+func (vetJSONParser) Detect(line []byte) bool { return string(line) == "{" }
+
+func (vetJSONParser) Parse(index *Index, dir string, lines [][]byte, at int) int {
+	end := at + 1
+	for end < len(lines) && string(lines[end]) != "}" {
+		end++
+	}
+	if end >= len(lines) {
+		return at
+	}
+
+	var packages map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(bytes.Join(lines[at:end+1], []byte("\n")), &packages); err != nil {
+		return at
+	}
+
+	for _, analyzers := range packages {
+		for analyzer, raw := range analyzers {
+			// analyzers that failed have an {"error": ...} object instead
+			var diagnostics []VetDiagnostic
+			if err := json.Unmarshal(raw, &diagnostics); err != nil {
+				continue
+			}
+			for _, diagnostic := range diagnostics {
+				path, lineno, column, msg, ok := ParseFileLine([]byte(diagnostic.Posn + ": " + diagnostic.Message))
+				if !ok {
+					continue
+				}
+				_, file := index.File(dir, string(path))
+				if file.AddNote("vet/"+analyzer, lineno-1, column-1, msg) {
+					file.Stats.Add(msg)
+				}
+			}
+		}
+	}
+	return end
+}
@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestVetJSONParser(t *testing.T) {
+	checkParser(t, vetJSONParser{}, `
+{
+	"example.com/pkg": {
+		"printf": [
+			{
+				"posn": "/src/pkg/foo.go:10:2",
+				"message": "fmt.Println call has possible Printf formatting directive %d"
+			}
+		]
+	}
+}`, []string{
+		"pkg/foo.go:10:2: vet/printf: fmt.Println call has possible Printf formatting directive %d",
+	})
+}
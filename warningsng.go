@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+)
+
+// WarningsNGReport is the native JSON format of the Jenkins
+// Warnings Next Generation plugin.
+type WarningsNGReport struct {
+	Issues []WarningsNGIssue `json:"issues"`
+}
+
+// WarningsNGIssue is a single issue in a WarningsNGReport.
+type WarningsNGIssue struct {
+	FileName    string `json:"fileName"`
+	LineStart   int    `json:"lineStart"`
+	ColumnStart int    `json:"columnStart,omitempty"`
+	Category    string `json:"category,omitempty"`
+	Type        string `json:"type"`
+	Severity    string `json:"severity"`
+	Message     string `json:"message"`
+	PackageName string `json:"packageName,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// warningsNGSeverity maps severities to the plugin's ERROR, HIGH, NORMAL
+// and LOW, where LOW is used for informational notes.
+var warningsNGSeverity = map[Severity]string{
+	Info:    "LOW",
+	Warning: "NORMAL",
+	Error:   "ERROR",
+}
+
+// WriteWarningsNG writes all notes in the Warnings NG native JSON format,
+// the tool is used as the issue type, e.g. to trend inlining and escape
+// counts per build:
+//
+//	recordIssues tool: issues(pattern: 'annotations.json')
+func (index *Index) WriteWarningsNG(w io.Writer) error {
+	report := &WarningsNGReport{Issues: []WarningsNGIssue{}}
+
+	files := index.SortedFiles()
+
+	for _, file := range files {
+		var pkg string
+		var funcs []funcRange
+		if file.Source == nil && filepath.Ext(file.AbsPath) == ".go" {
+			pkg, funcs = index.goFuncs(file.AbsPath)
+		}
+		for _, note := range file.Notes {
+			report.Issues = append(report.Issues, WarningsNGIssue{
+				FileName:    filepath.ToSlash(file.Path),
+				LineStart:   note.Line + 1,
+				ColumnStart: note.Column + 1,
+				Category:    NoteCategory(&note),
+				Type:        note.Tool,
+				Severity:    warningsNGSeverity[note.Severity],
+				Message:     string(note.Message),
+				PackageName: pkg,
+				Fingerprint: Fingerprint(file.Path, funcs, &note),
+			})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(report)
+}
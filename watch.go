@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// NotesUpdate is pushed to clients watching a file when notes are added.
+type NotesUpdate struct {
+	Generation int      `json:"generation"`
+	Notes      []NoteAt `json:"notes"`
+}
+
+// NoteAt is a note with its line number.
+type NoteAt struct {
+	Line int `json:"line"` // 1 is the first line
+	LineNote
+}
+
+// serveWatch pushes the notes added to a file over a WebSocket, e.g. while
+// a long build is being followed.
+func (server *Server) serveWatch(w http.ResponseWriter, r *http.Request) {
+	path := r.FormValue("path")
+	if path == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "No path specified.")
+		return
+	}
+	filter, err := parseFilter(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%v", err)
+		return
+	}
+	if !IsWebSocket(r) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "WebSocket connection expected.")
+		return
+	}
+	// browsers don't apply CORS to WebSockets
+	if !server.allowedOrigin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, "Origin %q not allowed.", r.Header.Get("Origin"))
+		return
+	}
+
+	conn, rw, err := UpgradeWebSocket(w, r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return
+	}
+	defer conn.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		DiscardWebSocket(rw.Reader)
+		close(closed)
+	}()
+
+	// the client has loaded the file already
+	sent := make(map[noteKey]bool)
+	update, updated := server.newNotes(path, filter, sent, false)
+	for {
+		if len(update.Notes) > 0 {
+			data, err := json.Marshal(update)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				return
+			}
+			if err := WriteWebSocketText(rw.Writer, data); err != nil {
+				return
+			}
+		}
+
+		select {
+		case <-closed:
+			return
+		case <-updated:
+			update, updated = server.newNotes(path, filter, sent, true)
+		}
+	}
+}
+
+// newNotes returns the notes of path matching filter that are not in sent
+// and adds them to it, together with a channel closed on the next update.
+// When include is false the notes are only added to sent.
+func (server *Server) newNotes(path string, filter Filter, sent map[noteKey]bool, include bool) (*NotesUpdate, chan struct{}) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if server.updated == nil {
+		server.updated = make(chan struct{})
+	}
+	update := &NotesUpdate{Generation: server.Index.Generation, Notes: []NoteAt{}}
+
+	file, ok := server.Index.Lookup(path)
+	if !ok {
+		return update, server.updated
+	}
+	var funcs []funcRange
+	for i := range file.Notes {
+		note := &file.Notes[i]
+		key := noteKey{note.Tool, note.Line, note.Column, string(note.Message)}
+		if sent[key] || !filter.Match(note) {
+			continue
+		}
+		sent[key] = true
+		if include {
+			if funcs == nil {
+				funcs = server.Index.fileFuncs(file)
+			}
+			update.Notes = append(update.Notes, NoteAt{note.Line + 1, newLineNote(note, Fingerprint(file.Path, funcs, note))})
+		}
+	}
+	return update, server.updated
+}
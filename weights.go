@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Weights are the importance of packages, e.g. their share of a CPU
+// profile, so that annotations of hot packages are ranked before those of
+// cold test helpers. A weights file has a package import path and its
+// weight on each line, "*" sets the weight of the packages not listed:
+//
+//	example.com/app/codec 40
+//	example.com/app/internal/testutil 0.1
+//	* 1
+type Weights struct {
+	Packages map[string]float64
+	Default  float64
+
+	// root is the directory package paths are matched against, see InPackage.
+	root string
+	dirs map[string]float64
+}
+
+// ParseWeights parses a weights file for packages in root.
+func ParseWeights(root string, data []byte) (*Weights, error) {
+	weights := &Weights{
+		Packages: make(map[string]float64),
+		Default:  1,
+		root:     root,
+		dirs:     make(map[string]float64),
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a package and its weight", n)
+		}
+		weight, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("line %d: invalid weight %q", n, fields[1])
+		}
+		if fields[0] == "*" {
+			weights.Default = weight
+		} else {
+			weights.Packages[fields[0]] = weight
+		}
+	}
+	return weights, scanner.Err()
+}
+
+// Weight returns the weight of the package of the file at path, the one of
+// the longest matching import path. A nil Weights weighs everything 1.
+func (weights *Weights) Weight(path string) float64 {
+	if weights == nil {
+		return 1
+	}
+	dir := filepath.Dir(path)
+	if weight, ok := weights.dirs[dir]; ok {
+		return weight
+	}
+	weight, matched := weights.Default, ""
+	for pkg, w := range weights.Packages {
+		if len(pkg) > len(matched) && InPackage(pkg, weights.root, dir) {
+			weight, matched = w, pkg
+		}
+	}
+	weights.dirs[dir] = weight
+	return weight
+}
+
+// WeightedStats are Stats summed over files with the weights of their
+// packages.
+type WeightedStats [statCount][2]float64
+
+func (stats WeightedStats) String() string {
+	r := ""
+	for i, v := range stats {
+		if i > 0 {
+			r += " "
+		}
+		r += strconv.FormatFloat(v[0], 'f', 1, 64) + "/" + strconv.FormatFloat(v[1], 'f', 1, 64)
+	}
+	return r
+}
+
+// WeightedStats returns the stats of all files weighted by Weights.
+func (index *Index) WeightedStats() WeightedStats {
+	var stats WeightedStats
+	for _, file := range index.Files {
+		weight := index.Weights.Weight(file.AbsPath)
+		for i, v := range file.Stats {
+			stats[i][0] += float64(v[0]) * weight
+			stats[i][1] += float64(v[1]) * weight
+		}
+	}
+	return stats
+}